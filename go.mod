@@ -6,5 +6,8 @@ require (
 	github.com/alecthomas/kong v0.2.17
 	github.com/caarlos0/env v3.5.0+incompatible
 	github.com/jedib0t/go-pretty/v6 v6.2.4
+	golang.org/x/term v0.10.0
+	golang.org/x/text v0.13.0
 	gopkg.in/dutchcoders/goftp.v1 v1.0.0-20170301105846-ed59a591ce14
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,18 +1,34 @@
 package main
 
 import (
-	"encoding/json"
+	"fmt"
 	"guysports/playerstats/pkg/cmd"
 	"guysports/playerstats/pkg/helper"
-	"guysports/playerstats/pkg/types"
+	"os"
+	"path/filepath"
 
 	"github.com/alecthomas/kong"
 	"github.com/caarlos0/env"
 )
 
 var cli struct {
-	Display cmd.Display `cmd:"" help:"Show the player statistics for requested players"`
-	Match   cmd.Match   `cmd:"" help:"Display fixture statistics"`
+	Offline  bool `help:"Serve all remote sources from the local cache without network access"`
+	Validate bool `help:"Report unknown and missing fields found in the feeds"`
+
+	Display   cmd.Display     `cmd:"" help:"Show the player statistics for requested players"`
+	Match     cmd.Match       `cmd:"" help:"Display fixture statistics"`
+	Diff      cmd.Diff        `cmd:"" help:"Compare two snapshots of the player data"`
+	Compare   cmd.Compare     `cmd:"" help:"Compare the statistics of two or more players side by side"`
+	Table     cmd.LeagueTable `cmd:"" help:"Show the league table computed from completed matches"`
+	Fixtures  cmd.Fixtures    `cmd:"" help:"Show upcoming fixtures and their difficulty for each team"`
+	Prices    cmd.Prices      `cmd:"" help:"Show player price history, the biggest price movers and predicted price changes"`
+	Optimize  cmd.Optimize    `cmd:"" help:"Pick the best squad within a budget, team limit and formations"`
+	Team      cmd.MyTeam      `cmd:"" help:"Show the players, stats and upcoming fixtures of the team in the team file"`
+	Transfers cmd.Transfers   `cmd:"" help:"Suggest the best transfers for the team in the team file"`
+	Captain   cmd.Captain     `cmd:"" help:"Rank captain candidates by their expected points in the next game week"`
+	Tui       cmd.Tui         `cmd:"" help:"Browse the players, fixtures and a watchlist in an interactive terminal view"`
+	Serve     cmd.Serve       `cmd:"" help:"Serve the players, matches and tables as a JSON REST API"`
+	Backtest  cmd.Backtest    `cmd:"" help:"Replay past game weeks to measure how well player ranking strategies predict points"`
 }
 
 func main() {
@@ -20,30 +36,32 @@ func main() {
 	env.Parse(&globals)
 	ctx := kong.Parse(&cli)
 
-	// Obtain the squad information
-	data, err := helper.GetJSON(globals.SquadSource)
+	// Configure the local snapshot cache for remote sources
+	globals.Offline = cli.Offline
+	if globals.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		ctx.FatalIfErrorf(err)
+		globals.CacheDir = filepath.Join(dir, "playerstats")
+	}
+	helper.ConfigureCache(helper.CacheOptions{
+		Dir:     globals.CacheDir,
+		MaxAge:  globals.CacheMaxAge,
+		Offline: globals.Offline,
+	})
+
+	// Obtain the squad and competition information
+	err := cmd.LoadReferenceData(&globals)
 	ctx.FatalIfErrorf(err)
-	squads := []types.Squad{}
-	_ = json.Unmarshal(data, &squads)
 
-	// Obtain the competition information
-	data, err = helper.GetJSON(globals.CompetitionSource)
+	err = ctx.Run(&globals)
 	ctx.FatalIfErrorf(err)
-	competitions := []types.Competition{}
-	_ = json.Unmarshal(data, &competitions)
 
-	// Extract competitions into map
-	globals.CompetitionMap = map[int]types.Competition{}
-	for _, competition := range competitions {
-		globals.CompetitionMap[competition.ID] = competition
+	// Report any records skipped from the feeds
+	summary, failed := helper.FeedSummary(cli.Validate)
+	if summary != "" {
+		fmt.Fprintln(os.Stderr, summary)
 	}
-
-	// Extract squads into map
-	globals.SquadMap = map[int]types.Squad{}
-	for _, squad := range squads {
-		globals.SquadMap[squad.ID] = squad
+	if failed {
+		ctx.Exit(1)
 	}
-
-	err = ctx.Run(&globals)
-	ctx.FatalIfErrorf(err)
 }
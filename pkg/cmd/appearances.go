@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/helper"
+	"guysports/playerstats/pkg/types"
+	"sort"
+	"strconv"
+)
+
+// attachAppearances sets each player's matches to the ones they actually played in, including
+// matches for former clubs. Appearances come from the player's match scores, then any match
+// where they scored, assisted or were booked, then any game week with a weekly score but no
+// match, which is assumed to be their current squad's match. Disagreements between the player
+// feed and the game week feed are reported against the player feed source
+func attachAppearances(players []types.Player, matchweeks []types.MatchWeek, source string) {
+	matches := map[int]types.Match{}
+	matchGw := map[int]int{}
+	gwMatches := map[int][]types.Match{}
+	involved := map[int][]int{}
+	for _, week := range matchweeks {
+		for _, match := range week.MatchesInWeek {
+			matches[match.Id] = match
+			matchGw[match.Id] = week.Id
+			gwMatches[week.Id] = append(gwMatches[week.Id], match)
+			seen := map[int]bool{}
+			for _, event := range matchEvents(match) {
+				for _, id := range []int{event.PlayerId, event.AssistId} {
+					if id != 0 && !seen[id] {
+						seen[id] = true
+						involved[id] = append(involved[id], match.Id)
+					}
+				}
+			}
+		}
+	}
+
+	for i, player := range players {
+		name := fmt.Sprintf("player %d %s", player.Id, playerName(player))
+		appearances := map[string]types.Match{}
+		gwScores := map[int]int{}
+		for _, id := range sortedScoreKeys(player.InPlayStats.MatchScores) {
+			key := fmt.Sprintf("%d", id)
+			match, ok := matches[id]
+			if !ok {
+				helper.ReportInconsistency(source, "%s has a score for unknown match %d", name, id)
+				continue
+			}
+			appearances[key] = match
+			gwScores[matchGw[id]] += player.InPlayStats.MatchScores[key]
+		}
+
+		for _, id := range involved[player.Id] {
+			key := fmt.Sprintf("%d", id)
+			if _, ok := appearances[key]; ok {
+				continue
+			}
+			appearances[key] = matches[id]
+			helper.ReportInconsistency(source, "%s has events in match %d (GW%d) but no score for it", name, id, matchGw[id])
+		}
+
+		for _, gw := range sortedScoreKeys(player.InPlayStats.WeeklyScores) {
+			score := player.InPlayStats.WeeklyScores[fmt.Sprintf("%d", gw)]
+			if total, ok := gwScores[gw]; ok {
+				if total != score {
+					helper.ReportInconsistency(source, "%s has a GW%d score of %d but match scores totalling %d", name, gw, score, total)
+				}
+				continue
+			}
+			// A score of nothing and no match scores is a squad member who did not play
+			if score == 0 {
+				continue
+			}
+			inferred := false
+			for _, match := range gwMatches[gw] {
+				if match.Status == "complete" && (match.HomeSquadId == player.SquadId || match.AwaySquadId == player.SquadId) {
+					appearances[fmt.Sprintf("%d", match.Id)] = match
+					inferred = true
+				}
+			}
+			if inferred {
+				helper.ReportInconsistency(source, "%s has a GW%d score but no match scores, assuming their current squad's match", name, gw)
+			} else {
+				helper.ReportInconsistency(source, "%s has a GW%d score of %d but no match in that game week", name, gw, score)
+			}
+		}
+		players[i].Matches = appearances
+	}
+}
+
+// sortedScoreKeys returns the numeric keys of a score map in order, ignoring any that are not numbers
+func sortedScoreKeys(scores map[string]int) []int {
+	keys := []int{}
+	for key := range scores {
+		if n, err := strconv.Atoi(key); err == nil {
+			keys = append(keys, n)
+		}
+	}
+	sort.Ints(keys)
+	return keys
+}
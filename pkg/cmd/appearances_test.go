@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestAttachAppearances(t *testing.T) {
+	// The player scored for squad 2 in match 223 before moving to squad 1, which plays in game weeks 1
+	// to 3 and has its game week 4 match still to play
+	withGoal := testResult(2, 2, 3, 1, 0)
+	withGoal.Stats.GoalScorers = []types.GoalScorer{{ScorerId: 7, Min: 10, Type: "goal"}}
+	unplayed := testResult(4, 1, 3, 0, 0)
+	unplayed.Status = "scheduled"
+	matchweeks := testMatchWeeks(testResult(1, 1, 2, 1, 0), withGoal, testResult(2, 1, 4, 0, 0), testResult(3, 4, 1, 2, 2), unplayed)
+
+	tests := []struct {
+		name         string
+		matchScores  map[string]int
+		weeklyScores map[string]int
+		want         []int
+	}{
+		{"match scores", map[string]int{"112": 6}, map[string]int{"1": 6}, []int{112, 223}},
+		{"events for a former squad", map[string]int{}, map[string]int{}, []int{223}},
+		{"weekly score without match scores", map[string]int{}, map[string]int{"3": 4}, []int{223, 341}},
+		{"weekly score of nothing", map[string]int{}, map[string]int{"1": 0, "3": 0}, []int{223}},
+		{"weekly score in a week not yet played", map[string]int{}, map[string]int{"4": 2}, []int{223}},
+	}
+	for _, test := range tests {
+		players := []types.Player{{
+			Id:          7,
+			SquadId:     1,
+			Positions:   []int{3},
+			InPlayStats: types.Stats{MatchScores: test.matchScores, WeeklyScores: test.weeklyScores},
+		}}
+		attachAppearances(players, matchweeks, "players.json")
+		got := []int{}
+		for key := range players[0].Matches {
+			id, _ := strconv.Atoi(key)
+			got = append(got, id)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: appearances %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// backtestStrategies predict a player's points in a game week from what was known before it, given
+// the player cut down to their earlier matches, the projector built from earlier results and the
+// number of fixtures their squad plays in the week
+var backtestStrategies = map[string]func(player types.Player, projections *projector, fixtures int) float64{
+	// projected is the projection model used by display --projected
+	"projected": func(player types.Player, projections *projector, fixtures int) float64 {
+		total := 0.0
+		for _, fixture := range projections.projectFixtures(player) {
+			if fixture.Gw == projections.gws[0] {
+				total += fixture.Points
+			}
+		}
+		return total
+	},
+	// form is the points per appearance weighted towards recent matches times the appearance rate
+	"form": func(player types.Player, projections *projector, fixtures int) float64 {
+		perAppearance, rate := projections.form(player)
+		return perAppearance * rate * float64(fixtures)
+	},
+	// last_5_avg is the average of the last five match scores
+	"last_5_avg": func(player types.Player, projections *projector, fixtures int) float64 {
+		scores := priorScores(player, projections)
+		if len(scores) > 5 {
+			scores = scores[len(scores)-5:]
+		}
+		return averageScore(scores) * float64(fixtures)
+	},
+	// average is the season points per appearance
+	"average": func(player types.Player, projections *projector, fixtures int) float64 {
+		return averageScore(priorScores(player, projections)) * float64(fixtures)
+	},
+}
+
+type (
+	Backtest struct {
+		Strategy   string   `help:"strategy used to rank and pick players, one of projected, form, last_5_avg or average" enum:"projected,form,last_5_avg,average" default:"projected"`
+		Baselines  []string `help:"strategies to compare against" default:"last_5_avg,average"`
+		From       int      `help:"first game week to replay, earlier weeks only provide data" default:"2"`
+		Budget     float64  `help:"total cost of the starting eleven picked each week in millions, using the prices of that week" default:"83"`
+		MaxPerTeam int      `help:"maximum number of players from one team, 0 for no limit" default:"3"`
+		Formations []string `help:"valid starting formations as defenders-midfielders-forwards" default:"3-4-3,3-5-2,4-3-3,4-4-2,4-5-1,5-3-2,5-4-1"`
+		Weeks      bool     `help:"show the results of each game week"`
+		Where      string   `help:"filter expression to select the players that can be picked, as for display --where"`
+		Output     string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// backtestWeek is how well a strategy did in one game week
+	backtestWeek struct {
+		Gw          int      `json:"gw"`
+		Strategy    string   `json:"strategy"`
+		Players     int      `json:"players"`
+		MAE         float64  `json:"mae"`
+		Correlation float64  `json:"rank_correlation"`
+		Points      int      `json:"points"`
+		Picks       []string `json:"picks"`
+	}
+
+	// backtestResult is how well a strategy did over all the replayed game weeks
+	backtestResult struct {
+		Strategy    string  `json:"strategy"`
+		Weeks       int     `json:"weeks"`
+		MAE         float64 `json:"mae"`
+		Correlation float64 `json:"rank_correlation"`
+		Points      int     `json:"points"`
+	}
+)
+
+func (b *Backtest) Run(globals *Globals) error {
+	strategies := []string{b.Strategy}
+	seen := map[string]bool{b.Strategy: true}
+	for _, baseline := range b.Baselines {
+		if _, ok := backtestStrategies[baseline]; !ok {
+			return fmt.Errorf("unknown baseline %q, use one of projected, form, last_5_avg or average", baseline)
+		}
+		if !seen[baseline] {
+			seen[baseline] = true
+			strategies = append(strategies, baseline)
+		}
+	}
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+	formations, err := parseFormations(b.Formations)
+	if err != nil {
+		return err
+	}
+	teams := newTeamIndex(globals.SquadMap, globals.TeamAliases)
+	expression, err := parseFilters(nil, b.Where, teams)
+	if err != nil {
+		return err
+	}
+	constraints := squadConstraints{
+		budget:       int(math.Round(b.Budget * 1e6)),
+		formations:   formations,
+		maxPerSquad:  b.MaxPerTeam,
+		startersOnly: true,
+	}
+
+	attachGameweeks(players, newGameweekCalendar(matchweeks), upcomingGameweeks(matchweeks, 0), globals.CompetitionMap)
+
+	// Squad stats cover the whole season so only results are used for goal rates
+	squads := map[int]types.Squad{}
+	for id, squad := range globals.SquadMap {
+		squad.Stats = types.SquadStats{}
+		squads[id] = squad
+	}
+
+	weeks := []backtestWeek{}
+	for _, gw := range completedGameweeks(matchweeks) {
+		if gw < b.From {
+			continue
+		}
+		prior := priorMatchWeeks(matchweeks, gw)
+		projections := newProjector(prior, 1, squads)
+		if len(projections.gws) == 0 || projections.gws[0] != gw {
+			continue
+		}
+		fixtures := map[int]int{}
+		for _, run := range projections.runs {
+			fixtures[run.SquadId] = len(run.Fixtures)
+		}
+		gwMatches := map[int]bool{}
+		for _, week := range matchweeks {
+			if week.Id == gw {
+				for _, match := range week.MatchesInWeek {
+					gwMatches[match.Id] = true
+				}
+			}
+		}
+
+		// Players whose squad plays in the week, as they were known before it, and which of them the
+		// filter allows to be picked given only what was known then
+		priors := []types.Player{}
+		actual := []float64{}
+		pickable := map[int]bool{}
+		for _, player := range players {
+			if fixtures[player.SquadId] == 0 {
+				continue
+			}
+			points := 0
+			for key, score := range player.InPlayStats.MatchScores {
+				if id, err := strconv.Atoi(key); err == nil && gwMatches[id] {
+					points += score
+				}
+			}
+			prior := priorPlayer(player, projections, gw)
+			priors = append(priors, prior)
+			actual = append(actual, float64(points))
+			pickable[prior.Id] = expression == nil || expression.Match(playerRecord(&prior))
+		}
+
+		for _, strategy := range strategies {
+			predict := backtestStrategies[strategy]
+			predicted := make([]float64, len(priors))
+			candidates := []candidate{}
+			for i, player := range priors {
+				predicted[i] = predict(player, projections, fixtures[player.SquadId])
+				if !pickable[player.Id] {
+					continue
+				}
+				candidates = append(candidates, candidate{player: player, position: player.Positions[0], value: predicted[i]})
+			}
+			week := backtestWeek{
+				Gw:          gw,
+				Strategy:    strategy,
+				Players:     len(priors),
+				MAE:         meanAbsoluteError(predicted, actual),
+				Correlation: rankCorrelation(predicted, actual),
+				Picks:       []string{},
+			}
+			if selection, ok := solveSquad(candidates, constraints); ok {
+				for _, c := range selection.starters {
+					week.Picks = append(week.Picks, playerName(c.player))
+					for i, player := range priors {
+						if player.Id == c.player.Id {
+							week.Points += int(actual[i])
+						}
+					}
+				}
+			}
+			weeks = append(weeks, week)
+		}
+	}
+	if len(weeks) == 0 {
+		return fmt.Errorf("no completed game weeks from game week %d to replay", b.From)
+	}
+	results := summariseBacktest(strategies, weeks)
+
+	switch b.Output {
+	case "table":
+		if b.Weeks {
+			rows := []table.Row{}
+			for _, week := range weeks {
+				picks := strings.Join(week.Picks, ", ")
+				if len(week.Picks) == 0 {
+					picks = "no eleven within the limits"
+				}
+				rows = append(rows, table.Row{week.Gw, week.Strategy, fmt.Sprintf("%.2f", week.MAE), fmt.Sprintf("%.3f", week.Correlation), week.Points, picks})
+			}
+			renderDiffTable("Game weeks", table.Row{"GW", "Strategy", "MAE", "Rank Correlation", "Points", "Picks"}, rows)
+		}
+		rows := []table.Row{}
+		for _, result := range results {
+			rows = append(rows, table.Row{result.Strategy, result.Weeks, fmt.Sprintf("%.2f", result.MAE), fmt.Sprintf("%.3f", result.Correlation), result.Points})
+		}
+		renderDiffTable(fmt.Sprintf("Backtest from game week %d", b.From), table.Row{"Strategy", "Weeks", "MAE", "Rank Correlation", "Points"}, rows)
+		return nil
+	case "json":
+		if b.Weeks {
+			return writeJSON(map[string]interface{}{"strategies": results, "weeks": weeks})
+		}
+		return writeJSON(results)
+	}
+	records := [][]string{}
+	if b.Weeks {
+		for _, week := range weeks {
+			records = append(records, []string{fmt.Sprintf("%d", week.Gw), week.Strategy, fmt.Sprintf("%.3f", week.MAE), fmt.Sprintf("%.3f", week.Correlation), fmt.Sprintf("%d", week.Points), strings.Join(week.Picks, "; ")})
+		}
+		return writeRecords(b.Output, []string{"gw", "strategy", "mae", "rank_correlation", "points", "picks"}, records)
+	}
+	for _, result := range results {
+		records = append(records, []string{result.Strategy, fmt.Sprintf("%d", result.Weeks), fmt.Sprintf("%.3f", result.MAE), fmt.Sprintf("%.3f", result.Correlation), fmt.Sprintf("%d", result.Points)})
+	}
+	return writeRecords(b.Output, []string{"strategy", "weeks", "mae", "rank_correlation", "points"}, records)
+}
+
+// completedGameweeks returns the ids of the game weeks whose matches are all complete, in order
+func completedGameweeks(matchweeks []types.MatchWeek) []int {
+	gws := []int{}
+	for _, week := range matchweeks {
+		complete := len(week.MatchesInWeek) > 0
+		for _, match := range week.MatchesInWeek {
+			if match.Status != "complete" {
+				complete = false
+			}
+		}
+		if complete {
+			gws = append(gws, week.Id)
+		}
+	}
+	sort.Ints(gws)
+	return gws
+}
+
+// priorMatchWeeks returns the game weeks as they stood before game week gw, with its matches and
+// every later match not yet played
+func priorMatchWeeks(matchweeks []types.MatchWeek, gw int) []types.MatchWeek {
+	prior := []types.MatchWeek{}
+	for _, week := range matchweeks {
+		if week.Id >= gw {
+			matches := []types.Match{}
+			for _, match := range week.MatchesInWeek {
+				matches = append(matches, types.Match{
+					Id:            match.Id,
+					Gw:            match.Gw,
+					CompetitionId: match.CompetitionId,
+					HomeSquadId:   match.HomeSquadId,
+					AwaySquadId:   match.AwaySquadId,
+					VenueId:       match.VenueId,
+					Status:        "scheduled",
+					Date:          match.Date,
+				})
+			}
+			week = types.MatchWeek{Id: week.Id, Status: "scheduled", MatchesInWeek: matches}
+		}
+		prior = append(prior, week)
+	}
+	return prior
+}
+
+// priorPlayer returns the player as they were known before game week gw. The match scores, totals,
+// averages, goals, assists, cards, clean sheets and price are worked out again from the earlier
+// matches and rounds. Stats the results do not give, such as star man awards, ranks and transfers,
+// are zeroed so that nothing from game week gw or later is seen.
+func priorPlayer(player types.Player, projections *projector, gw int) types.Player {
+	stats := types.Stats{
+		Prices:       priorRounds(player.InPlayStats.Prices, gw+1),
+		Scores:       priorRounds(player.InPlayStats.Scores, gw),
+		MatchScores:  map[string]int{},
+		WeeklyScores: priorRounds(player.InPlayStats.WeeklyScores, gw),
+		DraftScores:  priorRounds(player.InPlayStats.DraftScores, gw),
+	}
+	for key, score := range player.InPlayStats.MatchScores {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		match, ok := projections.matches[id]
+		if !ok || match.Status != "complete" {
+			continue
+		}
+		stats.MatchScores[key] = score
+		stats.GamesPlayed++
+		stats.TotalPoints += score
+		for _, event := range matchEvents(match) {
+			if event.PlayerId == player.Id {
+				switch event.Kind {
+				case "goal", "penalty":
+					stats.Goals++
+				case "yellow card", "red card":
+					stats.Cards++
+				}
+			}
+			if event.AssistId == player.Id {
+				stats.Assists++
+			}
+		}
+		if (match.HomeSquadId == player.SquadId && match.AwayScore == 0) || (match.AwaySquadId == player.SquadId && match.HomeScore == 0) {
+			stats.CleanSheets++
+		}
+	}
+	player.InPlayStats = stats
+	player.InPlayEPLStats = types.EPLStats{}
+
+	scores := priorScores(player, projections)
+	for i, score := range scores {
+		if i == 0 || score > stats.HighScore {
+			stats.HighScore = score
+		}
+		if i == 0 || score < stats.LowScore {
+			stats.LowScore = score
+		}
+	}
+	stats.AvgPoints = int(math.Round(averageScore(scores)))
+	last3, last5 := scores, scores
+	if len(last3) > 3 {
+		last3 = last3[len(last3)-3:]
+	}
+	if len(last5) > 5 {
+		last5 = last5[len(last5)-5:]
+	}
+	stats.Last3Avg, stats.Last5Avg = float32(averageScore(last3)), float32(averageScore(last5))
+	stats.Last3ThisSeasonAvg, stats.Last5ThisSeasonAvg = int(math.Round(averageScore(last3))), int(math.Round(averageScore(last5)))
+	if price, ok := stats.Prices[fmt.Sprintf("%d", gw)]; ok {
+		player.Cost = price
+	}
+	player.InPlayStats = stats
+	return player
+}
+
+// priorRounds returns the values keyed by a round before round
+func priorRounds(values map[string]int, round int) map[string]int {
+	prior := map[string]int{}
+	for key, value := range values {
+		if id, err := strconv.Atoi(key); err == nil && id < round {
+			prior[key] = value
+		}
+	}
+	return prior
+}
+
+// priorScores returns the player's match scores in date order
+func priorScores(player types.Player, projections *projector) []int {
+	type appearance struct {
+		date  string
+		score int
+	}
+	appearances := []appearance{}
+	for key, score := range player.InPlayStats.MatchScores {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		appearances = append(appearances, appearance{projections.matches[id].Date, score})
+	}
+	sort.SliceStable(appearances, func(i, j int) bool {
+		return appearances[i].date < appearances[j].date
+	})
+	scores := []int{}
+	for _, a := range appearances {
+		scores = append(scores, a.score)
+	}
+	return scores
+}
+
+func averageScore(scores []int) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	total := 0
+	for _, score := range scores {
+		total += score
+	}
+	return float64(total) / float64(len(scores))
+}
+
+func meanAbsoluteError(predicted []float64, actual []float64) float64 {
+	if len(actual) == 0 {
+		return 0
+	}
+	total := 0.0
+	for i := range actual {
+		total += math.Abs(predicted[i] - actual[i])
+	}
+	return total / float64(len(actual))
+}
+
+// rankCorrelation is the Spearman rank correlation of the predicted and actual points, ties sharing
+// their average rank
+func rankCorrelation(predicted []float64, actual []float64) float64 {
+	x, y := ranks(predicted), ranks(actual)
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	meanX, meanY := 0.0, 0.0
+	for i := range x {
+		meanX += x[i] / n
+		meanY += y[i] / n
+	}
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		varianceX += (x[i] - meanX) * (x[i] - meanX)
+		varianceY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+// ranks returns the rank of each value, from 1 for the lowest
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+	result := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			result[order[i]] = rank
+		}
+		start = end + 1
+	}
+	return result
+}
+
+// summariseBacktest averages the error and correlation of each strategy over the game weeks and adds
+// up the points of its picks
+func summariseBacktest(strategies []string, weeks []backtestWeek) []backtestResult {
+	results := []backtestResult{}
+	for _, strategy := range strategies {
+		result := backtestResult{Strategy: strategy}
+		for _, week := range weeks {
+			if week.Strategy != strategy {
+				continue
+			}
+			result.Weeks++
+			result.MAE += week.MAE
+			result.Correlation += week.Correlation
+			result.Points += week.Points
+		}
+		if result.Weeks > 0 {
+			result.MAE /= float64(result.Weeks)
+			result.Correlation /= float64(result.Weeks)
+		}
+		results = append(results, result)
+	}
+	return results
+}
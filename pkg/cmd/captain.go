@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+const (
+	// starManBonus and sevenPlusBonus are how much expected points rise for a player who is star man
+	// or rated seven or more in every match they play
+	starManBonus   = 0.5
+	sevenPlusBonus = 0.25
+)
+
+type (
+	Captain struct {
+		PlayerNames []string `arg:"" optional:"" help:"names or ids of the candidates, default is the team in the team file"`
+		File        string   `help:"YAML or JSON file listing the team's players by name or id and the bank in millions, defaults to $PLAYERSTATS_TEAM_FILE"`
+		Top         int      `help:"number of candidates to show, 0 for all" default:"0"`
+		Output      string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// captainPick is a candidate's expected points in the next game week and what they come from
+	captainPick struct {
+		Rank     int      `json:"rank"`
+		Id       int      `json:"id"`
+		Name     string   `json:"name"`
+		Team     string   `json:"team"`
+		Fixtures []string `json:"fixtures"`
+		Expected float64  `json:"expected_points"`
+		Factors  []string `json:"factors"`
+	}
+)
+
+func (c *Captain) Run(globals *Globals) error {
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+
+	candidates := []types.Player{}
+	if len(c.PlayerNames) > 0 {
+		for _, name := range c.PlayerNames {
+			player, err := resolvePlayer(players, name)
+			if err != nil {
+				return err
+			}
+			candidates = append(candidates, player)
+		}
+	} else {
+		team, err := loadTeam(globals, c.File, players, teamMaxPerSquad)
+		if err != nil {
+			return err
+		}
+		candidates = team.players
+	}
+
+	projections := newProjector(matchweeks, 1, globals.SquadMap)
+	if len(projections.gws) == 0 {
+		return fmt.Errorf("there are no upcoming game weeks")
+	}
+	gw := projections.gws[0]
+	picks := []captainPick{}
+	for _, player := range candidates {
+		picks = append(picks, rateCaptain(player, projections, globals.SquadMap))
+	}
+	sort.SliceStable(picks, func(i, j int) bool {
+		return picks[i].Expected > picks[j].Expected
+	})
+	if c.Top > 0 && len(picks) > c.Top {
+		picks = picks[:c.Top]
+	}
+	for i := range picks {
+		picks[i].Rank = i + 1
+	}
+
+	switch c.Output {
+	case "table":
+		rows := []table.Row{}
+		for _, pick := range picks {
+			role := ""
+			switch pick.Rank {
+			case 1:
+				role = " (C)"
+			case 2:
+				role = " (VC)"
+			}
+			rows = append(rows, table.Row{pick.Rank, pick.Name + role, pick.Team, strings.Join(pick.Fixtures, "\n"), fmt.Sprintf("%.2f", pick.Expected), strings.Join(pick.Factors, "\n")})
+		}
+		renderDiffTable(fmt.Sprintf("Captain picks for game week %d", gw), table.Row{"Rank", "Player", "Team", "Fixtures", "Expected", "Factors"}, rows)
+		return nil
+	case "json":
+		return writeJSON(picks)
+	}
+	records := [][]string{}
+	for _, pick := range picks {
+		records = append(records, []string{
+			fmt.Sprintf("%d", pick.Rank),
+			fmt.Sprintf("%d", pick.Id),
+			pick.Name,
+			pick.Team,
+			strings.Join(pick.Fixtures, " "),
+			fmt.Sprintf("%.2f", pick.Expected),
+			strings.Join(pick.Factors, "; "),
+		})
+	}
+	return writeRecords(c.Output, []string{"rank", "id", "name", "team", "fixtures", "expected_points", "factors"}, records)
+}
+
+// rateCaptain works out a player's expected points in the next game week from their recent average,
+// how often they are star man or rated seven or more, and how many goals each opponent concedes at
+// home or away, adding up the fixtures of a double game week
+func rateCaptain(player types.Player, projections *projector, squads map[int]types.Squad) captainPick {
+	stats := player.InPlayStats
+	pick := captainPick{
+		Id:       player.Id,
+		Name:     playerName(player),
+		Team:     player.Team,
+		Fixtures: []string{},
+		Factors:  []string{},
+	}
+
+	form := float64(stats.Last3Avg+stats.Last5Avg) / 2
+	pick.Factors = append(pick.Factors, fmt.Sprintf("form %.2f from last 3 avg %.2f and last 5 avg %.2f", form, stats.Last3Avg, stats.Last5Avg))
+	if form == 0 && stats.GamesPlayed > 0 {
+		form = float64(stats.TotalPoints) / float64(stats.GamesPlayed)
+		pick.Factors = append(pick.Factors, fmt.Sprintf("no recent average, season average %.2f", form))
+	}
+
+	quality := 1.0
+	if stats.GamesPlayed > 0 {
+		starRate := math.Min(1, float64(stats.StarManAwards)/float64(stats.GamesPlayed))
+		sevenRate := math.Min(1, float64(stats.SevenPlusRatings)/float64(stats.GamesPlayed))
+		quality += starManBonus*starRate + sevenPlusBonus*sevenRate
+		if stats.StarManAwards > 0 {
+			pick.Factors = append(pick.Factors, fmt.Sprintf("star man in %.0f%% of games, %+.0f%%", starRate*100, starManBonus*starRate*100))
+		}
+		if stats.SevenPlusRatings > 0 {
+			pick.Factors = append(pick.Factors, fmt.Sprintf("rated 7+ in %.0f%% of games, %+.0f%%", sevenRate*100, sevenPlusBonus*sevenRate*100))
+		}
+	}
+
+	fixtures := projections.runs[player.SquadId].Fixtures
+	if len(fixtures) == 0 {
+		pick.Factors = append(pick.Factors, "no fixture this game week")
+	}
+	if len(fixtures) > 1 {
+		pick.Factors = append(pick.Factors, fmt.Sprintf("plays %d times this game week", len(fixtures)))
+	}
+	for _, fixture := range fixtures {
+		pick.Fixtures = append(pick.Fixtures, describeSquadFixture(fixture, squads))
+		opponent := math.Max(minOpponentFactor, math.Min(maxOpponentFactor, projections.attackFactor(fixture.OpponentId)))
+		venue, where := 1-homeBoost, "away"
+		if fixture.Home {
+			venue, where = 1+homeBoost, "at home"
+		}
+		pick.Factors = append(pick.Factors, fmt.Sprintf("%s %s, conceding %.2f a game, %+.0f%%", fixture.Opponent, where,
+			projections.concededRate[fixture.OpponentId], (opponent*venue-1)*100))
+		pick.Expected += form * quality * opponent * venue
+	}
+	pick.Expected = math.Round(pick.Expected*100) / 100
+	return pick
+}
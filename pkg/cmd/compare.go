@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"golang.org/x/term"
+)
+
+type (
+	Compare struct {
+		PlayerNames []string `arg:"" help:"names or ids of the players to compare"`
+		Fixtures    int      `help:"number of upcoming fixtures to show for each player" default:"3"`
+	}
+)
+
+var (
+	// lowerIsBetter lists the compared rows where the smallest value is the best
+	lowerIsBetter = map[string]bool{
+		"cost":                  true,
+		"cards":                 true,
+		"epl_cards":             true,
+		"round_rank":            true,
+		"season_rank":           true,
+		"monthly_transfers_out": true,
+	}
+
+	// unranked lists the compared rows where 0 means the player has no value rather than the best one
+	unranked = map[string]bool{
+		"round_rank":  true,
+		"season_rank": true,
+	}
+
+	highlight = text.Colors{text.FgGreen, text.Bold}
+)
+
+func (c *Compare) Run(globals *Globals) error {
+	if len(c.PlayerNames) < 2 {
+		return fmt.Errorf("at least two players are needed to compare")
+	}
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+
+	// Resolve each requested player to exactly one player
+	compared := []types.Player{}
+	for _, name := range c.PlayerNames {
+		player, err := resolvePlayer(players, name)
+		if err != nil {
+			return err
+		}
+		compared = append(compared, player)
+	}
+
+	// Only highlight the best values on a terminal, so piped output stays plain
+	colour := term.IsTerminal(int(os.Stdout.Fd()))
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{""}
+	for _, player := range compared {
+		header = append(header, playerName(player))
+	}
+	t.AppendHeader(header)
+
+	// Descriptive rows
+	for _, name := range []string{"team", "position", "status"} {
+		row := table.Row{name}
+		for i := range compared {
+			row = append(row, playerFields[name].value(&compared[i]))
+		}
+		t.AppendRow(row)
+	}
+	t.AppendSeparator()
+
+	// Statistic rows with the best value highlighted
+	names := []string{"cost", "points_per_million", "points_per_game"}
+	names = append(names, statFieldNames(reflect.TypeOf(types.Stats{}), "")...)
+	names = append(names, statFieldNames(reflect.TypeOf(types.EPLStats{}), "epl_")...)
+	for _, name := range names {
+		values := []float64{}
+		for i := range compared {
+			values = append(values, playerFields[name].value(&compared[i]).(float64))
+		}
+		t.AppendRow(compareRow(name, values, lowerIsBetter[name], unranked[name], colour))
+	}
+	t.AppendSeparator()
+
+	// Recent form and upcoming fixtures
+	row := table.Row{"form"}
+	for _, player := range compared {
+		scores := weeklyScores(player)
+		form := []string{}
+		for _, gw := range lastGameweeks(scores, 5) {
+			form = append(form, strconv.Itoa(scores[gw]))
+		}
+		row = append(row, strings.Join(form, " "))
+	}
+	t.AppendRow(row)
+	row = table.Row{"next fixtures"}
+	for _, player := range compared {
+		fixtures := []string{}
+		for _, match := range upcomingMatches(matchweeks, player.SquadId, c.Fixtures) {
+			fixtures = append(fixtures, describeFixture(match, player.SquadId, globals.SquadMap))
+		}
+		row = append(row, strings.Join(fixtures, "\n"))
+	}
+	t.AppendRow(row)
+	t.AppendSeparator()
+
+	// Head to head scores for every gameweek any of the players scored in
+	gameweeks := map[int]bool{}
+	for _, player := range compared {
+		for gw := range weeklyScores(player) {
+			gameweeks[gw] = true
+		}
+	}
+	gws := []int{}
+	for gw := range gameweeks {
+		gws = append(gws, gw)
+	}
+	sort.Ints(gws)
+	for _, gw := range gws {
+		values := []float64{}
+		for _, player := range compared {
+			values = append(values, float64(weeklyScores(player)[gw]))
+		}
+		t.AppendRow(compareRow(fmt.Sprintf("gw %d", gw), values, false, false, colour))
+	}
+	t.Render()
+	return nil
+}
+
+// compareRow formats a row of values, highlighting the best unless every value is the same. Zero
+// values are left out of the comparison when skipZero is set, and colour turns the highlight on
+func compareRow(name string, values []float64, lowest bool, skipZero bool, colour bool) table.Row {
+	best := 0.0
+	compared := 0
+	allEqual := true
+	for _, value := range values {
+		if value != values[0] {
+			allEqual = false
+		}
+		if skipZero && value == 0 {
+			continue
+		}
+		if compared == 0 || (lowest && value < best) || (!lowest && value > best) {
+			best = value
+		}
+		compared++
+	}
+	row := table.Row{name}
+	for _, value := range values {
+		formatted := formatFieldValue(name, value)
+		if colour && !allEqual && compared > 0 && value == best {
+			formatted = highlight.Sprint(formatted)
+		}
+		row = append(row, formatted)
+	}
+	return row
+}
+
+// weeklyScores returns the player's points keyed by gameweek number
+func weeklyScores(player types.Player) map[int]int {
+	scores := map[int]int{}
+	for key, score := range player.InPlayStats.WeeklyScores {
+		gw, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		scores[gw] = score
+	}
+	return scores
+}
+
+// lastGameweeks returns up to n of the latest gameweeks in the scores, oldest first
+func lastGameweeks(scores map[int]int, n int) []int {
+	gws := []int{}
+	for gw := range scores {
+		gws = append(gws, gw)
+	}
+	sort.Ints(gws)
+	if len(gws) > n {
+		gws = gws[len(gws)-n:]
+	}
+	return gws
+}
+
+// upcomingMatches returns the next n matches for a squad that have not been completed, in date order
+func upcomingMatches(matchweeks []types.MatchWeek, squadId int, n int) []types.Match {
+	matches := []types.Match{}
+	for _, week := range matchweeks {
+		for _, match := range week.MatchesInWeek {
+			if match.Status == "complete" {
+				continue
+			}
+			if match.HomeSquadId == squadId || match.AwaySquadId == squadId {
+				matches = append(matches, match)
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Date < matches[j].Date
+	})
+	if n > 0 && len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches
+}
+
+// describeFixture shows a match from the point of view of a squad, e.g. "GW6 Chelsea (H)"
+func describeFixture(match types.Match, squadId int, squads map[int]types.Squad) string {
+	if match.HomeSquadId == squadId {
+		return fmt.Sprintf("GW%d %s (H)", match.Gw, squads[match.AwaySquadId].Name)
+	}
+	return fmt.Sprintf("GW%d %s (A)", match.Gw, squads[match.HomeSquadId].Name)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		lowest   bool
+		skipZero bool
+		colour   bool
+		want     []bool
+	}{
+		{"goals", []float64{3, 5, 1}, false, false, true, []bool{false, true, false}},
+		{"cost", []float64{7500000, 5000000, 5000000}, true, false, true, []bool{false, true, true}},
+		{"goals", []float64{2, 2, 2}, false, false, true, []bool{false, false, false}},
+		{"season_rank", []float64{0, 120, 45}, true, true, true, []bool{false, false, true}},
+		{"season_rank", []float64{0, 45}, true, true, true, []bool{false, true}},
+		{"season_rank", []float64{0, 0}, true, true, true, []bool{false, false}},
+		{"goals", []float64{3, 5, 1}, false, false, false, []bool{false, false, false}},
+	}
+	for _, test := range tests {
+		row := compareRow(test.name, test.values, test.lowest, test.skipZero, test.colour)
+		got := []bool{}
+		for _, cell := range row[1:] {
+			got = append(got, strings.Contains(cell.(string), "\x1b["))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %v: highlighted %v, want %v", test.name, test.values, got, test.want)
+		}
+	}
+}
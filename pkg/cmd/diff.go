@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/helper"
+	"guysports/playerstats/pkg/types"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type (
+	Diff struct {
+		From string `help:"snapshot to compare from: latest, previous, -N, a date or a players.json file" default:"previous"`
+		To   string `help:"snapshot to compare to: latest, previous, -N, a date or a players.json file" default:"latest"`
+		List bool   `help:"list the stored snapshots of the player data"`
+	}
+)
+
+func (d *Diff) Run(globals *Globals) error {
+	if d.List {
+		snapshots, err := helper.ListSnapshots(globals.Source)
+		if err != nil {
+			return err
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Ref", "Fetched", "Path"})
+		for i, snapshot := range snapshots {
+			t.AppendRow(table.Row{i - len(snapshots) + 1, snapshot.Fetched.Local().Format("2006-01-02 15:04:05"), snapshot.Path})
+		}
+		t.Render()
+		return nil
+	}
+
+	// Fetch the player data so the latest snapshot is current, only when it is compared
+	if d.From == "latest" || d.To == "latest" {
+		if _, err := helper.GetJSON(globals.Source); err != nil {
+			return err
+		}
+	}
+
+	from, fromName, err := loadPlayerSnapshot(globals, d.From)
+	if err != nil {
+		return err
+	}
+	to, toName, err := loadPlayerSnapshot(globals, d.To)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Changes from %s to %s\n", fromName, toName)
+	displayPlayerDiff(from, to)
+	return nil
+}
+
+// loadPlayerSnapshot reads the players from a file path or a stored snapshot reference
+func loadPlayerSnapshot(globals *Globals, ref string) (map[int]types.Player, string, error) {
+	path := ref
+	name := ref
+	if _, err := os.Stat(ref); err != nil {
+		snapshot, err := helper.FindSnapshot(globals.Source, ref)
+		if err != nil {
+			return nil, "", err
+		}
+		path = snapshot.Path
+		name = snapshot.Fetched.Local().Format("2006-01-02 15:04:05")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	players, err := decodePlayers(globals, path, data)
+	if err != nil {
+		return nil, "", err
+	}
+	playerMap := map[int]types.Player{}
+	for _, player := range players {
+		playerMap[player.Id] = player
+	}
+	return playerMap, name, nil
+}
+
+func displayPlayerDiff(from map[int]types.Player, to map[int]types.Player) {
+	// Process the players in a stable order
+	ids := []int{}
+	for id := range to {
+		ids = append(ids, id)
+	}
+	for id := range from {
+		if _, ok := to[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	added := []table.Row{}
+	removed := []table.Row{}
+	prices := []table.Row{}
+	statuses := []table.Row{}
+	locks := []table.Row{}
+	transfers := []table.Row{}
+	for _, id := range ids {
+		before, inFrom := from[id]
+		after, inTo := to[id]
+		switch {
+		case !inFrom:
+			added = append(added, table.Row{playerName(after), after.Team, formatCost(after.Cost), after.Status})
+			continue
+		case !inTo:
+			removed = append(removed, table.Row{playerName(before), before.Team, formatCost(before.Cost), before.Status})
+			continue
+		}
+		if before.Cost != after.Cost {
+			prices = append(prices, table.Row{playerName(after), after.Team, formatCost(before.Cost), formatCost(after.Cost), formatCostChange(after.Cost - before.Cost)})
+		}
+		if before.Status != after.Status {
+			statuses = append(statuses, table.Row{playerName(after), after.Team, before.Status, after.Status})
+		}
+		if before.Locked != after.Locked {
+			locks = append(locks, table.Row{playerName(after), after.Team, before.Locked, after.Locked})
+		}
+		inDelta := after.InPlayStats.MonthlyTransfersIn - before.InPlayStats.MonthlyTransfersIn
+		outDelta := after.InPlayStats.MonthlyTransfersOut - before.InPlayStats.MonthlyTransfersOut
+		if inDelta != 0 || outDelta != 0 {
+			transfers = append(transfers, table.Row{playerName(after), after.Team, inDelta, outDelta, inDelta - outDelta})
+		}
+	}
+
+	// Show the biggest transfer movements first
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return abs(transfers[i][4].(int)) > abs(transfers[j][4].(int))
+	})
+
+	renderDiffTable("New players", table.Row{"Player", "Team", "Cost", "Status"}, added)
+	renderDiffTable("Removed players", table.Row{"Player", "Team", "Cost", "Status"}, removed)
+	renderDiffTable("Price changes", table.Row{"Player", "Team", "Was", "Now", "Change"}, prices)
+	renderDiffTable("Status changes", table.Row{"Player", "Team", "Was", "Now"}, statuses)
+	renderDiffTable("Locked changes", table.Row{"Player", "Team", "Was", "Now"}, locks)
+	renderDiffTable("Transfer changes", table.Row{"Player", "Team", "In", "Out", "Net"}, transfers)
+}
+
+func renderDiffTable(title string, header table.Row, rows []table.Row) {
+	if len(rows) == 0 {
+		fmt.Printf("%s: none\n", title)
+		return
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(title)
+	t.AppendHeader(header)
+	t.AppendRows(rows)
+	t.Render()
+}
+
+func playerName(player types.Player) string {
+	return fmt.Sprintf("%s %s", player.FirstName, player.LastName)
+}
+
+func formatCost(cost int) string {
+	return fmt.Sprintf("£%.2fm", float64(cost)/1000000)
+}
+
+func formatCostChange(change int) string {
+	if change > 0 {
+		return "+" + formatCost(change)
+	}
+	if change == 0 {
+		return formatCost(0)
+	}
+	return "-" + formatCost(-change)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
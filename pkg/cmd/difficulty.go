@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"math"
+)
+
+// awayPenalty is added to an opponent's strength when the match is played at their ground
+const awayPenalty = 0.1
+
+// difficultyModel rates how hard a match against each squad is, from the squads' goals scored,
+// goals conceded and form rank, each scaled against the rest of the league
+type difficultyModel struct {
+	strength map[int]float64
+}
+
+func newDifficultyModel(squads map[int]types.Squad) *difficultyModel {
+	model := difficultyModel{strength: map[int]float64{}}
+	maxGoals, maxConceded, maxRank := 0, 0, 0
+	for _, squad := range squads {
+		if squad.Stats.Goals > maxGoals {
+			maxGoals = squad.Stats.Goals
+		}
+		if squad.Stats.Conceded > maxConceded {
+			maxConceded = squad.Stats.Conceded
+		}
+		if squad.Stats.Rank > maxRank {
+			maxRank = squad.Stats.Rank
+		}
+	}
+	for id, squad := range squads {
+		attack, defence, form := 0.5, 0.5, 0.5
+		if maxGoals > 0 {
+			attack = float64(squad.Stats.Goals) / float64(maxGoals)
+		}
+		if maxConceded > 0 {
+			defence = 1 - float64(squad.Stats.Conceded)/float64(maxConceded)
+		}
+		if maxRank > 1 && squad.Stats.Rank > 0 {
+			form = 1 - float64(squad.Stats.Rank-1)/float64(maxRank-1)
+		}
+		model.strength[id] = (attack + defence + form) / 3
+	}
+	return &model
+}
+
+// rating returns the difficulty of playing an opponent, from 1 for the easiest to 5 for the hardest
+func (m *difficultyModel) rating(opponentId int, home bool) float64 {
+	strength := m.strength[opponentId]
+	if !home {
+		strength += awayPenalty
+	}
+	strength = math.Max(0, math.Min(1, strength))
+	return 1 + 4*strength
+}
+
+// fixtureRating returns the difficulty of a match for a squad
+func (m *difficultyModel) fixtureRating(match types.Match, squadId int) float64 {
+	if match.HomeSquadId == squadId {
+		return m.rating(match.AwaySquadId, true)
+	}
+	return m.rating(match.HomeSquadId, false)
+}
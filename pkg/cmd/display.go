@@ -1,11 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
+	"embed"
 	"fmt"
-	"guysports/playerstats/pkg/helper"
+	"guysports/playerstats/pkg/filter"
 	"guysports/playerstats/pkg/types"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,38 +17,41 @@ import (
 	goftp "gopkg.in/dutchcoders/goftp.v1"
 )
 
+// playerTemplate is the template for a player's html page, built in unless --template-dir is given
+const playerTemplate = "player.template"
+
+//go:embed player.template
+var templates embed.FS
+
 type (
 	Display struct {
-		PlayerNames []string `help:"name of players whose stats to be viewed"`
-		Sort        string   `help:"what to sort the lists by, supported are position, team"`
+		PlayerNames []string `help:"names or ids of players whose stats to be viewed, matched on first, last or full name ignoring case and accents"`
+		Sort        []string `help:"what to sort the lists by, any player stat or position, team, points_per_million, with a :desc suffix or ~ prefix for descending, e.g. ~total_points or last_5_avg:desc,cost"`
+		Limit       int      `help:"maximum number of players to display, 0 for all"`
+		View        string   `help:"how to display players, full shows a table per player, summary shows one row per player" enum:"full,summary" default:"full"`
+		Columns     []string `help:"columns to show in the summary view, any player stat or field"`
+		PageSize    int      `help:"number of rows before the summary view header is repeated, 0 for no paging" default:"25"`
 		Filter      []string `help:"apply criteria to players to display, with separated filter=value,filter=value list"`
+		Where       string   `help:"filter expression, e.g. 'position == \"midfielder\" && cost <= 7.5 && last5avg > 6 || goals >= 10'"`
 		Matches     bool     `help:"temporary option to display match info"`
+		Events      bool     `help:"list the goals, assists and cards each player was involved in"`
+		Projected   bool     `help:"show each player's expected points in their upcoming fixtures, also available as the projected field"`
+		Weeks       int      `help:"number of upcoming game weeks to project points over" default:"3"`
 		Html        bool     `help:"format player info into html pages"`
+		TemplateDir string   `help:"directory holding a player.template to use for the html pages instead of the built in one"`
+		OutDir      string   `help:"directory the html pages are written to, created if it does not exist" default:"players"`
+		Output      string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
 	}
 )
 
 func (d *Display) Run(globals *Globals) error {
 	// Load in the statistics data from source
-	players := []types.Player{}
-	matchweeks := []types.MatchWeek{}
 	squadMap := globals.SquadMap
 	competitionMap := globals.CompetitionMap
-	var data []byte
-	var err error
-
-	// Obtain the player data
-	data, err = helper.GetJSON(globals.Source)
-	if err != nil {
-		return err
-	}
-	_ = json.Unmarshal(data, &players)
-
-	// Obtain the game week data
-	data, err = helper.GetJSON(globals.MatchesSource)
+	players, matchweeks, err := loadPlayerData(globals, d.Weeks)
 	if err != nil {
 		return err
 	}
-	_ = json.Unmarshal(data, &matchweeks)
 
 	// Extract matches from each week
 	matchesMap := map[string]types.Match{}
@@ -58,197 +62,199 @@ func (d *Display) Run(globals *Globals) error {
 		}
 	}
 
-	// The match Ids in the player struct are unreliable so get the matches by player squad id
-	for i, player := range players {
-		players[i].Matches = map[string]types.Match{}
-		for _, match := range matchesMap {
-			if player.SquadId == match.HomeSquadId || player.SquadId == match.AwaySquadId {
-				players[i].Matches[fmt.Sprintf("%d", match.Id)] = match
-			}
-		}
+	// Check any filters and only add player if filter is met
+	teams := newTeamIndex(squadMap, globals.TeamAliases)
+	expression, err := parseFilters(d.Filter, d.Where, teams)
+	if err != nil {
+		return err
+	}
+	sortKeys, err := parseSortKeys(d.Sort)
+	if err != nil {
+		return err
 	}
-
 	filteredPlayers := []types.Player{}
 
-	// Add team and position to each player
-	for i, player := range players {
-		// Check any filters and only add player if filter is met
-		filter := parseFilters(d.Filter)
-
+	for _, player := range players {
 		// Check player against filter
-		filteredPlayer := checkPlayerValid(&player, filter)
-		if filteredPlayer == nil {
+		if expression != nil && !expression.Match(playerRecord(&player)) {
 			continue
 		}
-		cost := float64(player.Cost) / 1000000
-		filteredPlayer.Team = types.Teams[player.SquadId]
-		filteredPlayer.Job = types.Position[player.Positions[0]]
-		filteredPlayer.CostDisp = fmt.Sprintf("&pound;%.2fm", cost)
-		filteredPlayers = append(filteredPlayers, *filteredPlayer)
-
-		players[i].Team = types.Teams[player.SquadId]
-		players[i].Job = types.Position[player.Positions[0]]
-		players[i].CostDisp = fmt.Sprintf("&pound;%.2fm", cost)
+		filteredPlayers = append(filteredPlayers, player)
 	}
 
 	if d.Html {
 		// Generate rendered player and match information
 		renderPlayers := []types.RenderedPlayer{}
 		for _, player := range players {
-			renderedPlayer := types.RenderedPlayer{
-				Id:          player.Id,
-				Name:        fmt.Sprintf("%s %s", player.FirstName, player.LastName),
-				Team:        player.Team,
-				Position:    player.Job,
-				Cost:        player.CostDisp,
-				TotalPoints: player.InPlayStats.TotalPoints,
-				GamesPlayed: player.InPlayStats.GamesPlayed,
-				StarMan:     player.InPlayStats.StarManAwards,
-				SevenPlus:   player.InPlayStats.SevenPlusRatings,
-				Goals:       player.InPlayStats.Goals,
-				Assists:     player.InPlayStats.Assists,
-				CleanSheets: player.InPlayStats.CleanSheets,
-				Cards:       player.InPlayStats.Cards,
-				Last3Avg:    player.InPlayStats.Last3Avg,
-				Last5Avg:    player.InPlayStats.Last5Avg,
-			}
-			// Generate rendered fixtures
-			renderedFixtures := []types.RenderedMatch{}
-			for _, match := range player.Matches {
-				if match.Status == "complete" {
-					fixture := types.RenderedMatch{
-						Gw:          match.Gw,
-						Competition: competitionMap[match.CompetitionId].Name,
-						Fixture:     fmt.Sprintf("%s v %s", squadMap[match.HomeSquadId].Name, squadMap[match.AwaySquadId].Name),
-						Result:      fmt.Sprintf("%d v %d", match.HomeScore, match.AwayScore),
-						Date:        strings.Split(match.Date, "T")[0],
-					}
-					renderedFixtures = append(renderedFixtures, fixture)
-				}
-			}
-
+			renderedPlayer := renderPlayer(player, competitionMap, squadMap)
+			renderedFixtures := renderedPlayer.TeamFixtures
 			sort.Slice(renderedFixtures, func(i, j int) bool {
 				if renderedFixtures[i].Date > renderedFixtures[j].Date {
 					return true
 				}
 				return false
 			})
-
-			renderedPlayer.TeamFixtures = renderedFixtures
 			renderPlayers = append(renderPlayers, renderedPlayer)
 		}
-		formatAsHtml(renderPlayers, globals.FtpPassword)
-		return nil
+		return formatAsHtml(renderPlayers, d.TemplateDir, d.OutDir, globals.FtpPassword)
 	}
 
-	if d.PlayerNames == nil || d.PlayerNames[0] == "all" {
-		displayPlayerInfo(filteredPlayers, matchesMap, competitionMap, squadMap, d.Sort)
-	} else {
-		selectPlayers := []types.Player{}
-		for _, player := range d.PlayerNames {
-			for _, playerstat := range players {
-				if player == playerstat.LastName {
-					selectPlayers = append(selectPlayers, playerstat)
-				}
+	selectPlayers := filteredPlayers
+	if d.PlayerNames != nil && d.PlayerNames[0] != "all" {
+		selectPlayers = []types.Player{}
+		for _, name := range d.PlayerNames {
+			player, err := resolvePlayer(players, name)
+			if err != nil {
+				return err
 			}
+			selectPlayers = append(selectPlayers, player)
 		}
-		displayPlayerInfo(selectPlayers, matchesMap, competitionMap, squadMap, d.Sort)
 	}
-	return nil
-}
 
-func displayPlayerInfo(players []types.Player, matches map[string]types.Match, competitions map[int]types.Competition, squads map[int]types.Squad, criteria string) {
-	if criteria != "" {
-		switch criteria {
-		case "position":
-			sort.Slice(players, func(i, j int) bool {
-				if players[i].Positions[0] < players[j].Positions[0] {
-					return true
-				}
-				if players[i].Positions[0] > players[j].Positions[0] {
-					return false
-				}
-				return players[i].SquadId < players[j].SquadId
-			})
-			break
-		case "team":
-			sort.Slice(players, func(i, j int) bool {
-				return players[i].SquadId < players[j].SquadId
-			})
-			break
+	sortPlayers(selectPlayers, sortKeys)
+	if d.Limit > 0 && len(selectPlayers) > d.Limit {
+		selectPlayers = selectPlayers[:d.Limit]
+	}
+
+	if d.View == "summary" {
+		columns, err := parseColumns(d.Columns)
+		if err != nil {
+			return err
+		}
+		if d.Projected && len(d.Columns) == 0 {
+			columns = append(columns, "projected")
 		}
+		return displayPlayerSummary(selectPlayers, columns, d.Output, d.PageSize)
 	}
+	if d.Output == "table" {
+		displayPlayerInfo(selectPlayers, matchesMap, competitionMap, squadMap, d.Events, d.Projected)
+		return nil
+	}
+	renderPlayers := []types.RenderedPlayer{}
+	for _, player := range selectPlayers {
+		renderedPlayer := renderPlayer(player, competitionMap, squadMap)
+		if d.Projected {
+			renderedPlayer.Projected = player.Projections
+		}
+		renderPlayers = append(renderPlayers, renderedPlayer)
+	}
+	return writePlayers(d.Output, renderPlayers)
+}
 
+func displayPlayerInfo(players []types.Player, matches map[string]types.Match, competitions map[int]types.Competition, squads map[int]types.Squad, events bool, projected bool) {
 	var pageSize int
 	for _, player := range players {
-		t := table.NewWriter()
+		t := playerInfoTable(player, competitions, squads, events, projected)
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"Position", "Player", "Team", "Cost", "Total Points"})
-		cost := float64(player.Cost) / 1000000
-		t.AppendRow(table.Row{player.Job, fmt.Sprintf("%s %s", player.FirstName, player.LastName), player.Team, fmt.Sprintf("£%.2fm", cost), player.InPlayStats.TotalPoints})
-		t.AppendRow(table.Row{"Games Played", "Star Man Awards", "+7 Ratings", "Goals", "Assists", "Clean Sheets", "Cards"})
-		t.AppendRow(table.Row{player.InPlayStats.GamesPlayed,
-			player.InPlayStats.StarManAwards,
-			player.InPlayStats.SevenPlusRatings,
-			player.InPlayStats.Goals,
-			player.InPlayStats.Assists,
-			player.InPlayStats.CleanSheets,
-			player.InPlayStats.Cards})
-		t.AppendRow(table.Row{"Date", "Competition", "Fixture", "Score", "Points Scored"})
-		gameRows := []table.Row{}
-		completedMatches := []types.Match{}
-		for _, match := range player.Matches {
-			if match.Status == "complete" {
-				completedMatches = append(completedMatches, match)
-			}
+		t.SetPageSize(pageSize)
+		t.Render()
+	}
+}
+
+// playerInfoTable builds the full view of a player's stats, match history, upcoming blank and double
+// game weeks and optionally their match events and projected points
+func playerInfoTable(player types.Player, competitions map[int]types.Competition, squads map[int]types.Squad, events bool, projected bool) table.Writer {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Position", "Player", "Team", "Cost", "Total Points"})
+	cost := float64(player.Cost) / 1000000
+	t.AppendRow(table.Row{player.Job, fmt.Sprintf("%s %s", player.FirstName, player.LastName), player.Team, fmt.Sprintf("£%.2fm", cost), player.InPlayStats.TotalPoints})
+	t.AppendRow(table.Row{"Games Played", "Star Man Awards", "+7 Ratings", "Goals", "Assists", "Clean Sheets", "Cards"})
+	t.AppendRow(table.Row{player.InPlayStats.GamesPlayed,
+		player.InPlayStats.StarManAwards,
+		player.InPlayStats.SevenPlusRatings,
+		player.InPlayStats.Goals,
+		player.InPlayStats.Assists,
+		player.InPlayStats.CleanSheets,
+		player.InPlayStats.Cards})
+	t.AppendRow(table.Row{"Date", "Competition", "Fixture", "Score", "Points Scored"})
+	gameRows := []table.Row{}
+	completedMatches := []types.Match{}
+	for _, match := range player.Matches {
+		if match.Status == "complete" {
+			completedMatches = append(completedMatches, match)
 		}
+	}
 
-		sort.Slice(completedMatches, func(i, j int) bool {
-			if completedMatches[i].Date > completedMatches[j].Date {
-				return false
-			}
-			return true
-		})
+	sort.Slice(completedMatches, func(i, j int) bool {
+		if completedMatches[i].Date > completedMatches[j].Date {
+			return false
+		}
+		return true
+	})
 
-		for _, match := range completedMatches {
-			gameRows = append(gameRows, table.Row{
-				strings.Split(match.Date, "T")[0],
-				competitions[match.CompetitionId].Name,
-				fmt.Sprintf("%s v %s", squads[match.HomeSquadId].Name, squads[match.AwaySquadId].Name),
-				fmt.Sprintf("%d v %d", match.HomeScore, match.AwayScore),
-				fmt.Sprintf("%d", player.InPlayStats.MatchScores[fmt.Sprintf("%d", match.Id)]),
-			})
+	for _, match := range completedMatches {
+		gameRows = append(gameRows, table.Row{
+			strings.Split(match.Date, "T")[0],
+			competitions[match.CompetitionId].Name,
+			fmt.Sprintf("%s v %s", squads[match.HomeSquadId].Name, squads[match.AwaySquadId].Name),
+			fmt.Sprintf("%d v %d", match.HomeScore, match.AwayScore),
+			fmt.Sprintf("%d", player.InPlayStats.MatchScores[fmt.Sprintf("%d", match.Id)]),
+		})
+	}
+	t.AppendRows(gameRows)
+	if events {
+		eventRows := playerEvents(player, competitions, squads)
+		if len(eventRows) > 0 {
+			t.AppendRow(table.Row{"Date", "Fixture", "Minute", "Event"})
+			t.AppendRows(eventRows)
 		}
-		t.AppendRows(gameRows)
-		t.SetPageSize(pageSize)
-		t.Render()
-		t = nil
 	}
-
+	if len(player.SpecialGameweeks) > 0 {
+		t.AppendRow(table.Row{"GW", "Game Week", "Matches", "Competitions"})
+		for _, special := range player.SpecialGameweeks {
+			t.AppendRow(table.Row{special.Gw, special.Kind, special.Matches, strings.Join(special.Competitions, ", ")})
+		}
+	}
+	if projected && len(player.Projections) > 0 {
+		t.AppendRow(table.Row{"GW", "Date", "Opponent", "Venue", "Expected Points"})
+		for _, fixture := range player.Projections {
+			venue := "A"
+			if fixture.Home {
+				venue = "H"
+			}
+			t.AppendRow(table.Row{fixture.Gw, fixture.Date, fixture.Opponent, venue, fmt.Sprintf("%.2f", fixture.Points)})
+		}
+	}
+	return t
 }
 
-func formatAsHtml(players []types.RenderedPlayer, password string) {
-	tmpl, err := template.ParseFiles("pkg/cmd/player.template")
+// formatAsHtml writes a page for each player to outDir, creating it if needed, from the built in
+// player template or the one in templateDir if set, then uploads the pages
+func formatAsHtml(players []types.RenderedPlayer, templateDir string, outDir string, password string) error {
+	var tmpl *template.Template
+	var err error
+	if templateDir != "" {
+		tmpl, err = template.ParseFiles(filepath.Join(templateDir, playerTemplate))
+	} else {
+		tmpl, err = template.ParseFS(templates, playerTemplate)
+	}
 	if err != nil {
-		fmt.Printf("Error templating %v\n", err)
-		return
+		return fmt.Errorf("loading the player template: %v", err)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
 	}
 	for _, player := range players {
-		f, err := os.Create(fmt.Sprintf("players/%d.php", player.Id))
+		filename := filepath.Join(outDir, fmt.Sprintf("%d.php", player.Id))
+		f, err := os.Create(filename)
 		if err != nil {
-			fmt.Printf("Error opening file %v\n", err)
-			return
+			return err
+		}
+		err = tmpl.Execute(f, player)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s: %v", filename, err)
 		}
-		tmpl.Execute(f, player)
-		f.Close()
 	}
-	err = uploadPlayerStats(players, password)
-	if err != nil {
-		fmt.Printf("error uploading playerstats %v\n", err)
+	if err := uploadPlayerStats(players, outDir, password); err != nil {
+		return fmt.Errorf("uploading player stats: %v", err)
 	}
+	return nil
 }
 
-func uploadPlayerStats(players []types.RenderedPlayer, password string) error {
+func uploadPlayerStats(players []types.RenderedPlayer, outDir string, password string) error {
 	// FTP file to guysports
 	ftp, err := goftp.Connect("ftp.guysports.co.uk:21")
 	if err != nil {
@@ -266,7 +272,7 @@ func uploadPlayerStats(players []types.RenderedPlayer, password string) error {
 	}
 
 	for _, player := range players {
-		localFilename := fmt.Sprintf("players/%d.php", player.Id)
+		localFilename := filepath.Join(outDir, fmt.Sprintf("%d.php", player.Id))
 		remoteFilename := fmt.Sprintf("%d.php", player.Id)
 
 		// Upload player stats
@@ -275,7 +281,9 @@ func uploadPlayerStats(players []types.RenderedPlayer, password string) error {
 			return err
 		}
 
-		if err := ftp.Stor(remoteFilename, file); err != nil {
+		err = ftp.Stor(remoteFilename, file)
+		file.Close()
+		if err != nil {
 			return err
 		}
 		fmt.Printf("uploaded %s for %s\n", remoteFilename, player.Name)
@@ -283,100 +291,53 @@ func uploadPlayerStats(players []types.RenderedPlayer, password string) error {
 	return nil
 }
 
-func parseFilters(filters []string) *types.PlayerFilter {
-	playerFilter := types.PlayerFilter{
-		ApplyFilter: false,
-	}
-	if filters == nil {
-		return &playerFilter
+// parseFilters combines the --where expression with any legacy filter=value pairs into a single expression,
+// resolving any team names in it to the full squad name
+func parseFilters(filters []string, where string, teams *teamIndex) (*filter.Expression, error) {
+	clauses := []string{}
+	if where != "" {
+		clauses = append(clauses, "("+where+")")
 	}
 	for _, filterValuePair := range filters {
 		pair := strings.Split(filterValuePair, "=")
 		if len(pair) != 2 {
-			continue
+			return nil, fmt.Errorf("invalid filter %q, expected filter=value", filterValuePair)
 		}
-		filter := pair[0]
 		value := pair[1]
-		switch filter {
-		case "team":
-			playerFilter.Team = value
-			playerFilter.ApplyFilter = true
-			break
+		switch pair[0] {
+		case "team", "position":
+			clauses = append(clauses, fmt.Sprintf("%s == %q", pair[0], value))
+			continue
+		}
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for filter %q: %v", filterValuePair, err)
+		}
+		switch pair[0] {
 		case "cost":
-			intValue, err := strconv.Atoi(value)
-			if err != nil {
-				break
-			}
-			playerFilter.Cost = intValue
-			playerFilter.ApplyFilter = true
-			break
+			clauses = append(clauses, fmt.Sprintf("cost <= %f", float64(intValue)/1000000))
 		case "points":
-			intValue, err := strconv.Atoi(value)
-			if err != nil {
-				break
-			}
-			playerFilter.Points = intValue
-			playerFilter.ApplyFilter = true
-			break
+			clauses = append(clauses, fmt.Sprintf("total_points >= %d", intValue))
 		case "games":
-			intValue, err := strconv.Atoi(value)
-			if err != nil {
-				break
-			}
-			playerFilter.Games = intValue
-			playerFilter.ApplyFilter = true
-			break
+			clauses = append(clauses, fmt.Sprintf("games_played >= %d", intValue))
 		case "average":
-			intValue, err := strconv.Atoi(value)
-			if err != nil {
-				break
-			}
-			playerFilter.Cost = intValue
-			playerFilter.ApplyFilter = true
-			break
-		case "position":
-			playerFilter.Job = value
-			playerFilter.ApplyFilter = true
-			break
+			clauses = append(clauses, fmt.Sprintf("avg_points >= %d", intValue))
+		default:
+			return nil, fmt.Errorf("unknown filter %q, supported are team, position, cost, points, games and average", pair[0])
 		}
 	}
-	return &playerFilter
-}
-
-func checkPlayerValid(player *types.Player, filter *types.PlayerFilter) *types.Player {
-	// Check each filter value in turn, and progress if not used or matches
-	if !filter.ApplyFilter {
-		return player
-	}
-	if filter.Average > 0 {
-		if player.InPlayStats.AvgPoints < filter.Average {
-			return nil
-		}
+	if len(clauses) == 0 {
+		return nil, nil
 	}
-	if filter.Cost > 0 {
-		if player.Cost > filter.Cost {
-			return nil
-		}
-	}
-	if filter.Points > 0 {
-		if player.InPlayStats.TotalPoints < filter.Points {
-			return nil
-		}
+	source := where
+	if len(clauses) > 1 || where == "" {
+		source = strings.Join(clauses, " && ")
 	}
-	if filter.Games > 0 {
-		if player.InPlayStats.GamesPlayed < filter.Games {
-			return nil
-		}
-	}
-	if filter.Team != "" {
-		if types.Teams[player.SquadId] != filter.Team {
-			return nil
-		}
-	}
-	if filter.Job != "" {
-		if types.Position[player.Positions[0]] != filter.Job {
-			return nil
-		}
+	expression, err := filter.Parse(source, playerFieldKinds())
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %v\navailable fields: %s", source, err, strings.Join(playerFieldNames(), ", "))
 	}
-	return player
+	// Accept team short names, abbreviations and aliases in place of the full name
+	expression.ResolveLiterals("team", teams.canonicalName)
+	return expression, nil
 }
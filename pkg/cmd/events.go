@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// matchEvent is a goal or card in a match
+type matchEvent struct {
+	Minute   int
+	Period   int
+	Kind     string
+	PlayerId int
+	AssistId int
+}
+
+// goalKind describes a goal from its type code, treating unrecognised codes as open play goals
+func goalKind(goalType string) string {
+	switch strings.ToUpper(goalType) {
+	case "O", "OG", "OWN", "OWN_GOAL":
+		return "own goal"
+	case "P", "PEN", "PENALTY":
+		return "penalty"
+	}
+	return "goal"
+}
+
+// matchEvents returns the goals and cards of a match in the order they happened
+func matchEvents(match types.Match) []matchEvent {
+	events := []matchEvent{}
+	for _, goal := range match.Stats.GoalScorers {
+		events = append(events, matchEvent{
+			Minute:   goal.Min,
+			Period:   goal.Period,
+			Kind:     goalKind(goal.Type),
+			PlayerId: goal.ScorerId,
+			AssistId: goal.AssistId,
+		})
+	}
+	for _, card := range match.Stats.YellowCards {
+		events = append(events, matchEvent{Minute: card.Min, Period: card.Period, Kind: "yellow card", PlayerId: card.PlayerId})
+	}
+	for _, card := range match.Stats.RedCards {
+		events = append(events, matchEvent{Minute: card.Min, Period: card.Period, Kind: "red card", PlayerId: card.PlayerId})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Period != events[j].Period {
+			return events[i].Period < events[j].Period
+		}
+		return events[i].Minute < events[j].Minute
+	})
+	return events
+}
+
+// renderEvents converts match events into their rendered form, resolving player names
+func renderEvents(match types.Match, players map[int]types.Player) []types.RenderedEvent {
+	rendered := []types.RenderedEvent{}
+	for _, event := range matchEvents(match) {
+		renderedEvent := types.RenderedEvent{
+			Minute: event.Minute,
+			Period: event.Period,
+			Event:  event.Kind,
+			Player: eventPlayerName(players, event.PlayerId),
+		}
+		if event.AssistId != 0 {
+			renderedEvent.Assist = eventPlayerName(players, event.AssistId)
+		}
+		if player, ok := players[event.PlayerId]; ok {
+			renderedEvent.Team = player.TeamAbbr
+		}
+		rendered = append(rendered, renderedEvent)
+	}
+	return rendered
+}
+
+// eventPlayerName returns a player's name, or their id if they are not in the player data
+func eventPlayerName(players map[int]types.Player, id int) string {
+	if player, ok := players[id]; ok {
+		return playerName(player)
+	}
+	return fmt.Sprintf("player %d", id)
+}
+
+// renderTimeline shows the events of a match
+func renderTimeline(match types.RenderedMatch) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(fmt.Sprintf("GW%d %s %s (%s)", match.Gw, match.Fixture, match.Result, match.Date))
+	t.AppendHeader(table.Row{"Min", "Period", "Event", "Player", "Team", "Assist"})
+	for _, event := range match.Events {
+		t.AppendRow(table.Row{event.Minute, event.Period, event.Event, event.Player, event.Team, event.Assist})
+	}
+	t.Render()
+}
+
+// playerEvents lists the goals, assists and cards a player was involved in across their completed matches
+func playerEvents(player types.Player, competitions map[int]types.Competition, squads map[int]types.Squad) []table.Row {
+	matches := []types.Match{}
+	for _, match := range player.Matches {
+		if match.Status == "complete" {
+			matches = append(matches, match)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Date < matches[j].Date
+	})
+
+	rows := []table.Row{}
+	for _, match := range matches {
+		rendered := renderMatch(match, competitions, squads)
+		for _, event := range matchEvents(match) {
+			kind := ""
+			switch {
+			case event.PlayerId == player.Id:
+				kind = event.Kind
+			case event.AssistId == player.Id:
+				kind = "assist"
+			default:
+				continue
+			}
+			rows = append(rows, table.Row{rendered.Date, rendered.Fixture, fmt.Sprintf("%d'", event.Minute), kind})
+		}
+	}
+	return rows
+}
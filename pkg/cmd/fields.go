@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/filter"
+	"guysports/playerstats/pkg/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// playerField is a named value of a player that can be used in filter expressions and sort keys
+type playerField struct {
+	kind  filter.Kind
+	value func(player *types.Player) interface{}
+}
+
+var playerFields = buildPlayerFields()
+
+// buildPlayerFields registers the descriptive player fields and derived metrics along with every numeric field in types.Stats and types.EPLStats
+func buildPlayerFields() map[string]playerField {
+	fields := map[string]playerField{
+		"id": {filter.Number, func(p *types.Player) interface{} { return float64(p.Id) }},
+		"cost": {filter.Number, func(p *types.Player) interface{} {
+			return float64(p.Cost) / 1000000
+		}},
+		"locked":     {filter.Number, func(p *types.Player) interface{} { return float64(p.Locked) }},
+		"name":       {filter.String, func(p *types.Player) interface{} { return fmt.Sprintf("%s %s", p.FirstName, p.LastName) }},
+		"first_name": {filter.String, func(p *types.Player) interface{} { return p.FirstName }},
+		"last_name":  {filter.String, func(p *types.Player) interface{} { return p.LastName }},
+		"status":     {filter.String, func(p *types.Player) interface{} { return p.Status }},
+		"team":       {filter.String, func(p *types.Player) interface{} { return p.Team }},
+		"team_short": {filter.String, func(p *types.Player) interface{} { return p.TeamShort }},
+		"team_abbr":  {filter.String, func(p *types.Player) interface{} { return p.TeamAbbr }},
+		"position":   {filter.String, func(p *types.Player) interface{} { return p.Job }},
+	}
+	// Derived metrics
+	fields["points_per_million"] = playerField{filter.Number, func(p *types.Player) interface{} {
+		if p.Cost == 0 {
+			return 0.0
+		}
+		return float64(p.InPlayStats.TotalPoints) / (float64(p.Cost) / 1000000)
+	}}
+	fields["points_per_game"] = playerField{filter.Number, func(p *types.Player) interface{} {
+		if p.InPlayStats.GamesPlayed == 0 {
+			return 0.0
+		}
+		return float64(p.InPlayStats.TotalPoints) / float64(p.InPlayStats.GamesPlayed)
+	}}
+	fields["net_transfers"] = playerField{filter.Number, func(p *types.Player) interface{} {
+		return float64(p.InPlayStats.MonthlyTransfersIn - p.InPlayStats.MonthlyTransfersOut)
+	}}
+	fields["projected"] = playerField{filter.Number, func(p *types.Player) interface{} { return p.Projected }}
+	// Matches the player's squad plays in each game week, e.g. gw_7_fixtures >= 2 for a double
+	for gw := 1; gw <= maxGameweeks; gw++ {
+		gw := gw
+		fields[gameweekField(gw)] = playerField{filter.Number, func(p *types.Player) interface{} {
+			return float64(p.GwFixtures[gw])
+		}}
+	}
+
+	addNumericFields(fields, reflect.TypeOf(types.Stats{}), "", func(p *types.Player) reflect.Value {
+		return reflect.ValueOf(p.InPlayStats)
+	})
+	addNumericFields(fields, reflect.TypeOf(types.EPLStats{}), "epl_", func(p *types.Player) reflect.Value {
+		return reflect.ValueOf(p.InPlayEPLStats)
+	})
+	return fields
+}
+
+// addNumericFields registers each int or float field of a stats struct under its json name
+func addNumericFields(fields map[string]playerField, t reflect.Type, prefix string, stats func(p *types.Player) reflect.Value) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Float32, reflect.Float64:
+		default:
+			continue
+		}
+		index := i
+		fields[prefix+statFieldName(field)] = playerField{filter.Number, func(p *types.Player) interface{} {
+			value := stats(p).Field(index)
+			switch value.Kind() {
+			case reflect.Int:
+				return float64(value.Int())
+			case reflect.Float32:
+				// Go through the shortest float32 representation so 4.07 does not become 4.070000171661377
+				f, _ := strconv.ParseFloat(strconv.FormatFloat(value.Float(), 'g', -1, 32), 64)
+				return f
+			}
+			return value.Float()
+		}}
+	}
+}
+
+// statFieldName is the json name of a stats field, or its snake cased Go name when the json name is not a valid identifier
+func statFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = snakeCase(field.Name)
+	}
+	return name
+}
+
+// statFieldNames returns the numeric field names of a stats struct in declaration order
+func statFieldNames(t reflect.Type, prefix string) []string {
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Float32, reflect.Float64:
+			names = append(names, prefix+statFieldName(field))
+		}
+	}
+	return names
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// playerFieldKinds returns the kind of each player field for parsing expressions
+func playerFieldKinds() map[string]filter.Kind {
+	kinds := map[string]filter.Kind{}
+	for name, field := range playerFields {
+		kinds[name] = field.kind
+	}
+	return kinds
+}
+
+// playerFieldNames returns the sorted player field names for help and error messages
+func playerFieldNames() []string {
+	names := []string{"gw_N_fixtures"}
+	for name := range playerFields {
+		if strings.HasPrefix(name, "gw_") && strings.HasSuffix(name, "_fixtures") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gameweekField is the name of the field holding the matches a player's squad plays in a game week
+func gameweekField(gw int) string {
+	return fmt.Sprintf("gw_%d_fixtures", gw)
+}
+
+// playerRecord exposes a player's fields to a filter expression
+func playerRecord(player *types.Player) filter.Record {
+	return func(name string) interface{} {
+		field, ok := playerFields[name]
+		if !ok {
+			return nil
+		}
+		return field.value(player)
+	}
+}
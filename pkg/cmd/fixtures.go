@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+type (
+	Fixtures struct {
+		Next   int      `help:"number of upcoming game weeks to show" default:"5"`
+		Team   []string `help:"only show these teams, by name, abbreviation or alias"`
+		Rank   bool     `help:"rank the teams by the easiest run of fixtures over the game weeks shown"`
+		Output string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// squadFixture is an upcoming match from the point of view of one squad
+	squadFixture struct {
+		Gw         int     `json:"gw"`
+		Date       string  `json:"date"`
+		OpponentId int     `json:"opponent_id"`
+		Opponent   string  `json:"opponent"`
+		Home       bool    `json:"home"`
+		Difficulty float64 `json:"difficulty"`
+	}
+
+	// squadRun is a squad's fixtures over the upcoming game weeks
+	squadRun struct {
+		SquadId    int            `json:"squad_id"`
+		Team       string         `json:"team"`
+		Fixtures   []squadFixture `json:"fixtures"`
+		Difficulty float64        `json:"average_difficulty"`
+		// Gameweeks are the blank and double game weeks among those shown
+		Gameweeks []types.SpecialGameweek `json:"blank_and_double_gameweeks"`
+	}
+)
+
+// difficultyColours shade fixtures from easiest to hardest
+var difficultyColours = []text.Colors{
+	{text.BgHiGreen, text.FgBlack},
+	{text.BgGreen, text.FgBlack},
+	{text.BgWhite, text.FgBlack},
+	{text.BgRed, text.FgWhite},
+	{text.BgHiRed, text.FgWhite},
+}
+
+func (f *Fixtures) Run(globals *Globals) error {
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+
+	// Select the squads to show
+	squadIds := []int{}
+	if len(f.Team) > 0 {
+		teams := newTeamIndex(globals.SquadMap, globals.TeamAliases)
+		for _, name := range f.Team {
+			id, ok := teams.lookup(name)
+			if !ok {
+				return fmt.Errorf("unknown team %q", name)
+			}
+			squadIds = append(squadIds, id)
+		}
+	} else {
+		for id := range globals.SquadMap {
+			squadIds = append(squadIds, id)
+		}
+	}
+
+	gws := upcomingGameweeks(matchweeks, f.Next)
+	runs := squadRuns(matchweeks, gws, squadIds, globals.SquadMap)
+	calendar := newGameweekCalendar(matchweeks)
+	for i, run := range runs {
+		runs[i].Gameweeks = calendar.special(run.SquadId, gws, globals.CompetitionMap)
+	}
+	if f.Rank {
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].Difficulty < runs[j].Difficulty
+		})
+	}
+
+	switch f.Output {
+	case "table":
+		renderFixtureGrid(runs, gws, globals.SquadMap, f.Rank)
+		rows := []table.Row{}
+		for _, run := range runs {
+			for _, special := range run.Gameweeks {
+				rows = append(rows, table.Row{special.Gw, run.Team, special.Kind, special.Matches, strings.Join(special.Competitions, ", ")})
+			}
+		}
+		if len(rows) > 0 {
+			sort.SliceStable(rows, func(i, j int) bool {
+				return rows[i][0].(int) < rows[j][0].(int)
+			})
+			renderDiffTable("Blank and double game weeks", table.Row{"GW", "Team", "Game Week", "Matches", "Competitions"}, rows)
+		}
+		return nil
+	case "json":
+		return writeJSON(runs)
+	}
+	records := [][]string{}
+	for _, run := range runs {
+		kinds := map[int]string{}
+		for _, special := range run.Gameweeks {
+			kinds[special.Gw] = special.Kind
+		}
+		for _, fixture := range run.Fixtures {
+			// Blank game weeks before the fixture get a record without an opponent
+			for _, gw := range gws {
+				if gw < fixture.Gw && kinds[gw] == "blank" {
+					records = append(records, []string{run.Team, fmt.Sprintf("%d", gw), "", "", "", "", "blank"})
+					kinds[gw] = "listed"
+				}
+			}
+			venue := "A"
+			if fixture.Home {
+				venue = "H"
+			}
+			kind := kinds[fixture.Gw]
+			if kind == "" {
+				kind = "single"
+			}
+			records = append(records, []string{
+				run.Team,
+				fmt.Sprintf("%d", fixture.Gw),
+				fixture.Date,
+				fixture.Opponent,
+				venue,
+				fmt.Sprintf("%.2f", fixture.Difficulty),
+				kind,
+			})
+		}
+		for _, gw := range gws {
+			if kinds[gw] == "blank" {
+				records = append(records, []string{run.Team, fmt.Sprintf("%d", gw), "", "", "", "", "blank"})
+			}
+		}
+	}
+	return writeRecords(f.Output, []string{"team", "gw", "date", "opponent", "venue", "difficulty", "gameweek"}, records)
+}
+
+// upcomingGameweeks returns the first n game weeks that still have matches to be completed
+func upcomingGameweeks(matchweeks []types.MatchWeek, n int) []int {
+	gws := []int{}
+	for _, week := range matchweeks {
+		for _, match := range week.MatchesInWeek {
+			if match.Status != "complete" {
+				gws = append(gws, week.Id)
+				break
+			}
+		}
+	}
+	sort.Ints(gws)
+	if n > 0 && len(gws) > n {
+		gws = gws[:n]
+	}
+	return gws
+}
+
+// blankDifficulty is the rating a game week without a match counts as in a run's average difficulty,
+// the same as the hardest fixture
+const blankDifficulty = 5
+
+// squadRuns collects each squad's unplayed matches in the given game weeks and rates their difficulty.
+// A game week in which the squad has no match counts as the hardest fixture towards the average.
+func squadRuns(matchweeks []types.MatchWeek, gws []int, squadIds []int, squads map[int]types.Squad) []squadRun {
+	model := newDifficultyModel(squads)
+	window := map[int]bool{}
+	for _, gw := range gws {
+		window[gw] = true
+	}
+
+	runs := []squadRun{}
+	for _, squadId := range squadIds {
+		run := squadRun{
+			SquadId:  squadId,
+			Team:     squads[squadId].Name,
+			Fixtures: []squadFixture{},
+		}
+		total := 0.0
+		played := map[int]bool{}
+		for _, week := range matchweeks {
+			if !window[week.Id] {
+				continue
+			}
+			for _, match := range week.MatchesInWeek {
+				if match.HomeSquadId != squadId && match.AwaySquadId != squadId {
+					continue
+				}
+				played[week.Id] = true
+				if match.Status == "complete" {
+					continue
+				}
+				fixture := squadFixture{
+					Gw:         week.Id,
+					Date:       strings.Split(match.Date, "T")[0],
+					Home:       match.HomeSquadId == squadId,
+					Difficulty: model.fixtureRating(match, squadId),
+				}
+				fixture.OpponentId = match.HomeSquadId
+				if fixture.Home {
+					fixture.OpponentId = match.AwaySquadId
+				}
+				fixture.Opponent = squads[fixture.OpponentId].Name
+				run.Fixtures = append(run.Fixtures, fixture)
+				total += fixture.Difficulty
+			}
+		}
+		blanks := 0
+		for gw := range window {
+			if !played[gw] {
+				blanks++
+			}
+		}
+		// A run with no fixtures is treated as the hardest
+		run.Difficulty = blankDifficulty
+		if len(run.Fixtures)+blanks > 0 {
+			run.Difficulty = (total + float64(blanks*blankDifficulty)) / float64(len(run.Fixtures)+blanks)
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Team < runs[j].Team
+	})
+	return runs
+}
+
+// squadRunMap returns the run of every squad in the given game weeks keyed by squad
+func squadRunMap(matchweeks []types.MatchWeek, gws []int, squads map[int]types.Squad) map[int]squadRun {
+	squadIds := []int{}
+	for id := range squads {
+		squadIds = append(squadIds, id)
+	}
+	runs := map[int]squadRun{}
+	for _, run := range squadRuns(matchweeks, gws, squadIds, squads) {
+		runs[run.SquadId] = run
+	}
+	return runs
+}
+
+// renderFixtureGrid shows a team by game week grid of opponents coloured by difficulty
+func renderFixtureGrid(runs []squadRun, gws []int, squads map[int]types.Squad, ranked bool) {
+	t := fixtureGrid(runs, gws, squads, ranked)
+	t.SetOutputMirror(os.Stdout)
+	t.Render()
+}
+
+func fixtureGrid(runs []squadRun, gws []int, squads map[int]types.Squad, ranked bool) table.Writer {
+	t := table.NewWriter()
+	header := table.Row{"Team"}
+	if ranked {
+		header = table.Row{"Rank", "Team"}
+	}
+	for _, gw := range gws {
+		header = append(header, fmt.Sprintf("GW%d", gw))
+	}
+	header = append(header, "Avg")
+	t.AppendHeader(header)
+
+	for i, run := range runs {
+		row := table.Row{run.Team}
+		if ranked {
+			row = table.Row{i + 1, run.Team}
+		}
+		for _, gw := range gws {
+			cells := []string{}
+			for _, fixture := range run.Fixtures {
+				if fixture.Gw != gw {
+					continue
+				}
+				cells = append(cells, difficultyColour(fixture.Difficulty).Sprintf(" %s ", describeSquadFixture(fixture, squads)))
+			}
+			if len(cells) == 0 {
+				cells = append(cells, "-")
+			}
+			row = append(row, strings.Join(cells, "\n"))
+		}
+		row = append(row, fmt.Sprintf("%.2f", run.Difficulty))
+		t.AppendRow(row)
+	}
+	return t
+}
+
+// difficultyColour returns the shading for a difficulty rating between 1 and 5
+func difficultyColour(difficulty float64) text.Colors {
+	index := int(math.Round(difficulty)) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(difficultyColours) {
+		index = len(difficultyColours) - 1
+	}
+	return difficultyColours[index]
+}
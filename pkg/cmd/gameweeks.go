@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+)
+
+// maxGameweeks is the highest game week with a gw_N_fixtures player field
+const maxGameweeks = 50
+
+// gameweekCalendar holds the matches each squad plays in each game week across every competition
+type gameweekCalendar struct {
+	// matches are keyed by game week then squad
+	matches map[int]map[int][]types.Match
+	// playing are the squads with a match in any game week
+	playing map[int]bool
+}
+
+func newGameweekCalendar(matchweeks []types.MatchWeek) *gameweekCalendar {
+	calendar := &gameweekCalendar{
+		matches: map[int]map[int][]types.Match{},
+		playing: map[int]bool{},
+	}
+	for _, week := range matchweeks {
+		if _, ok := calendar.matches[week.Id]; !ok {
+			calendar.matches[week.Id] = map[int][]types.Match{}
+		}
+		for _, match := range week.MatchesInWeek {
+			for _, squadId := range []int{match.HomeSquadId, match.AwaySquadId} {
+				calendar.matches[week.Id][squadId] = append(calendar.matches[week.Id][squadId], match)
+				calendar.playing[squadId] = true
+			}
+		}
+	}
+	return calendar
+}
+
+// fixtures returns the number of matches a squad plays in a game week
+func (c *gameweekCalendar) fixtures(gw int, squadId int) int {
+	return len(c.matches[gw][squadId])
+}
+
+// special returns the game weeks among gws in which a squad plays no match or more than one, naming
+// the competitions of the matches. Squads that play in no game week at all have no blanks.
+func (c *gameweekCalendar) special(squadId int, gws []int, competitions map[int]types.Competition) []types.SpecialGameweek {
+	specials := []types.SpecialGameweek{}
+	if !c.playing[squadId] {
+		return specials
+	}
+	for _, gw := range gws {
+		matches := c.matches[gw][squadId]
+		if len(matches) == 1 {
+			continue
+		}
+		special := types.SpecialGameweek{
+			Gw:           gw,
+			Kind:         "blank",
+			Matches:      len(matches),
+			Competitions: []string{},
+		}
+		if len(matches) > 1 {
+			special.Kind = "double"
+		}
+		for _, match := range matches {
+			name := competitions[match.CompetitionId].Name
+			if name == "" {
+				name = fmt.Sprintf("competition %d", match.CompetitionId)
+			}
+			special.Competitions = append(special.Competitions, name)
+		}
+		specials = append(specials, special)
+	}
+	return specials
+}
+
+// attachGameweeks sets the matches each player's squad plays in every game week and their blank and
+// double game weeks among gws
+func attachGameweeks(players []types.Player, calendar *gameweekCalendar, gws []int, competitions map[int]types.Competition) {
+	for i, player := range players {
+		players[i].GwFixtures = map[int]int{}
+		for gw := range calendar.matches {
+			players[i].GwFixtures[gw] = calendar.fixtures(gw, player.SquadId)
+		}
+		players[i].SpecialGameweeks = calendar.special(player.SquadId, gws, competitions)
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type (
+	LeagueTable struct {
+		Competition string `help:"competition to show the table for, by id, code or name, default is every competition"`
+		Venue       string `help:"only count matches played at this venue" enum:"all,home,away" default:"all"`
+		Last        int    `help:"only count each team's last N matches, 0 for all"`
+		Gw          int    `help:"show the table as it stood after this game week, 0 for the latest"`
+		Output      string `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// standing is a squad's record in a competition
+	standing struct {
+		SquadId      int      `json:"squad_id"`
+		Team         string   `json:"team"`
+		Played       int      `json:"played"`
+		Won          int      `json:"won"`
+		Drawn        int      `json:"drawn"`
+		Lost         int      `json:"lost"`
+		GoalsFor     int      `json:"goals_for"`
+		GoalsAgainst int      `json:"goals_against"`
+		Points       int      `json:"points"`
+		Form         []string `json:"form"`
+	}
+)
+
+var standingHeader = []string{"competition", "pos", "team", "p", "w", "d", "l", "gf", "ga", "gd", "pts", "form"}
+
+func (l *LeagueTable) Run(globals *Globals) error {
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+	competitionId := 0
+	if l.Competition != "" {
+		id, ok := lookupCompetition(globals.CompetitionMap, l.Competition)
+		if !ok {
+			return fmt.Errorf("unknown competition %q", l.Competition)
+		}
+		competitionId = id
+	}
+
+	standings := leagueStandings(matchweeks, competitionId, l.Venue, l.Last, l.Gw, globals.SquadMap)
+	competitionIds := []int{}
+	for id := range standings {
+		competitionIds = append(competitionIds, id)
+	}
+	sort.Ints(competitionIds)
+
+	tables := map[string][]standing{}
+	records := [][]string{}
+	for _, id := range competitionIds {
+		name := globals.CompetitionMap[id].Name
+		switch l.Output {
+		case "table":
+			renderStandings(tableTitle(name, l), standings[id])
+		case "json":
+			tables[name] = standings[id]
+		default:
+			for _, record := range standingRecords(standings[id]) {
+				records = append(records, append([]string{name}, record...))
+			}
+		}
+	}
+	switch l.Output {
+	case "table":
+		return nil
+	case "json":
+		return writeJSON(tables)
+	}
+	return writeRecords(l.Output, standingHeader, records)
+}
+
+// leagueStandings computes the table of each competition, or only competitionId if set, counting
+// matches at the venue up to game week gw and only each squad's last matches if last is set
+func leagueStandings(matchweeks []types.MatchWeek, competitionId int, venue string, last int, gw int, squads map[int]types.Squad) map[int][]standing {
+	// Gather each squad's matches for the requested venue, by competition
+	homeForm, awayForm := loadForm(matchweeks, gw)
+	squadMatches := map[int]map[int][]types.Match{}
+	addMatches := func(form map[int][]types.Match) {
+		for squadId, matches := range form {
+			for _, match := range matches {
+				if competitionId != 0 && match.CompetitionId != competitionId {
+					continue
+				}
+				if squadMatches[match.CompetitionId] == nil {
+					squadMatches[match.CompetitionId] = map[int][]types.Match{}
+				}
+				squadMatches[match.CompetitionId][squadId] = append(squadMatches[match.CompetitionId][squadId], match)
+			}
+		}
+	}
+	if venue != "away" {
+		addMatches(homeForm)
+	}
+	if venue != "home" {
+		addMatches(awayForm)
+	}
+
+	standings := map[int][]standing{}
+	for id, matches := range squadMatches {
+		standings[id] = computeStandings(matches, last, squads)
+	}
+	return standings
+}
+
+// computeStandings totals up each squad's matches, only counting the last n in date order if n is set
+func computeStandings(squadMatches map[int][]types.Match, n int, squads map[int]types.Squad) []standing {
+	standings := []standing{}
+	for squadId, matches := range squadMatches {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Date < matches[j].Date
+		})
+		if n > 0 && len(matches) > n {
+			matches = matches[len(matches)-n:]
+		}
+		s := standing{
+			SquadId: squadId,
+			Team:    squads[squadId].Name,
+			Form:    []string{},
+		}
+		for _, match := range matches {
+			scored, conceded := match.HomeScore, match.AwayScore
+			if match.AwaySquadId == squadId {
+				scored, conceded = conceded, scored
+			}
+			s.Played++
+			s.GoalsFor += scored
+			s.GoalsAgainst += conceded
+			switch {
+			case scored > conceded:
+				s.Won++
+				s.Points += 3
+				s.Form = append(s.Form, "W")
+			case scored == conceded:
+				s.Drawn++
+				s.Points++
+				s.Form = append(s.Form, "D")
+			default:
+				s.Lost++
+				s.Form = append(s.Form, "L")
+			}
+		}
+		standings = append(standings, s)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.GoalsFor-a.GoalsAgainst != b.GoalsFor-b.GoalsAgainst {
+			return a.GoalsFor-a.GoalsAgainst > b.GoalsFor-b.GoalsAgainst
+		}
+		if a.GoalsFor != b.GoalsFor {
+			return a.GoalsFor > b.GoalsFor
+		}
+		return a.Team < b.Team
+	})
+	return standings
+}
+
+func tableTitle(name string, l *LeagueTable) string {
+	qualifiers := []string{}
+	if l.Venue != "all" {
+		qualifiers = append(qualifiers, l.Venue+" only")
+	}
+	if l.Last > 0 {
+		qualifiers = append(qualifiers, fmt.Sprintf("last %d games", l.Last))
+	}
+	if l.Gw > 0 {
+		qualifiers = append(qualifiers, fmt.Sprintf("after game week %d", l.Gw))
+	}
+	if len(qualifiers) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(qualifiers, ", "))
+}
+
+func standingRecords(standings []standing) [][]string {
+	records := [][]string{}
+	for i, s := range standings {
+		records = append(records, []string{
+			fmt.Sprintf("%d", i+1),
+			s.Team,
+			fmt.Sprintf("%d", s.Played),
+			fmt.Sprintf("%d", s.Won),
+			fmt.Sprintf("%d", s.Drawn),
+			fmt.Sprintf("%d", s.Lost),
+			fmt.Sprintf("%d", s.GoalsFor),
+			fmt.Sprintf("%d", s.GoalsAgainst),
+			fmt.Sprintf("%d", s.GoalsFor-s.GoalsAgainst),
+			fmt.Sprintf("%d", s.Points),
+			strings.Join(lastForm(s.Form, 5), ""),
+		})
+	}
+	return records
+}
+
+func lastForm(form []string, n int) []string {
+	if len(form) > n {
+		return form[len(form)-n:]
+	}
+	return form
+}
+
+func renderStandings(title string, standings []standing) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(title)
+	t.AppendHeader(table.Row{"Pos", "Team", "P", "W", "D", "L", "GF", "GA", "GD", "Pts", "Form"})
+	for _, record := range standingRecords(standings) {
+		row := table.Row{}
+		for _, value := range record {
+			row = append(row, value)
+		}
+		t.AppendRow(row)
+	}
+	t.Render()
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"reflect"
+	"testing"
+)
+
+var testSquads = map[int]types.Squad{
+	1: {ID: 1, Name: "Arsenal"},
+	2: {ID: 2, Name: "Brentford"},
+	3: {ID: 3, Name: "Chelsea"},
+	4: {ID: 4, Name: "Liverpool"},
+}
+
+// testResult is a complete match in game week gw of competition 1, with a date that orders the
+// matches by game week
+func testResult(gw int, home int, away int, homeScore int, awayScore int) types.Match {
+	return types.Match{
+		Id:            gw*100 + home*10 + away,
+		Gw:            gw,
+		CompetitionId: 1,
+		HomeSquadId:   home,
+		AwaySquadId:   away,
+		Status:        "complete",
+		Date:          fmt.Sprintf("2022-08-%02dT15:00:00", gw),
+		HomeScore:     homeScore,
+		AwayScore:     awayScore,
+	}
+}
+
+func testMatchWeeks(matches ...types.Match) []types.MatchWeek {
+	weeks := map[int]*types.MatchWeek{}
+	order := []int{}
+	for _, match := range matches {
+		if weeks[match.Gw] == nil {
+			weeks[match.Gw] = &types.MatchWeek{Id: match.Gw, Status: "complete"}
+			order = append(order, match.Gw)
+		}
+		weeks[match.Gw].MatchesInWeek = append(weeks[match.Gw].MatchesInWeek, match)
+	}
+	matchweeks := []types.MatchWeek{}
+	for _, gw := range order {
+		matchweeks = append(matchweeks, *weeks[gw])
+	}
+	return matchweeks
+}
+
+func TestLeagueStandingsOrder(t *testing.T) {
+	scheduled := testResult(3, 4, 1, 0, 0)
+	scheduled.Status = "scheduled"
+	cup := testResult(2, 3, 4, 5, 0)
+	cup.CompetitionId = 2
+
+	tests := []struct {
+		name    string
+		matches []types.Match
+		venue   string
+		last    int
+		gw      int
+		want    []string
+	}{
+		{
+			name:    "points first",
+			matches: []types.Match{testResult(1, 1, 2, 1, 0), testResult(1, 3, 4, 0, 0)},
+			want:    []string{"Arsenal", "Chelsea", "Liverpool", "Brentford"},
+		},
+		{
+			name:    "goal difference breaks a tie on points",
+			matches: []types.Match{testResult(1, 1, 2, 1, 0), testResult(1, 3, 4, 3, 0)},
+			want:    []string{"Chelsea", "Arsenal", "Brentford", "Liverpool"},
+		},
+		{
+			name:    "goals scored break a tie on goal difference",
+			matches: []types.Match{testResult(1, 1, 2, 1, 0), testResult(1, 3, 4, 3, 2)},
+			want:    []string{"Chelsea", "Arsenal", "Liverpool", "Brentford"},
+		},
+		{
+			name:    "name breaks a tie on everything else",
+			matches: []types.Match{testResult(1, 4, 2, 1, 1), testResult(1, 3, 1, 1, 1)},
+			want:    []string{"Arsenal", "Brentford", "Chelsea", "Liverpool"},
+		},
+		{
+			name:    "home matches only",
+			matches: []types.Match{testResult(1, 1, 2, 1, 0), testResult(1, 3, 4, 0, 2)},
+			venue:   "home",
+			want:    []string{"Arsenal", "Chelsea"},
+		},
+		{
+			name:    "away matches only",
+			matches: []types.Match{testResult(1, 1, 2, 1, 0), testResult(1, 3, 4, 0, 2)},
+			venue:   "away",
+			want:    []string{"Liverpool", "Brentford"},
+		},
+		{
+			name:    "last matches only",
+			matches: []types.Match{testResult(1, 1, 2, 4, 0), testResult(2, 2, 1, 0, 1), testResult(3, 1, 2, 0, 3)},
+			last:    2,
+			want:    []string{"Brentford", "Arsenal"},
+		},
+		{
+			name:    "up to a game week",
+			matches: []types.Match{testResult(1, 1, 2, 0, 1), testResult(2, 1, 2, 2, 0), testResult(3, 1, 2, 0, 3)},
+			gw:      2,
+			want:    []string{"Arsenal", "Brentford"},
+		},
+		{
+			name:    "unplayed matches and other competitions are left out",
+			matches: []types.Match{testResult(1, 1, 2, 0, 1), testResult(2, 3, 4, 0, 0), cup, scheduled},
+			want:    []string{"Brentford", "Chelsea", "Liverpool", "Arsenal"},
+		},
+	}
+	for _, test := range tests {
+		venue := test.venue
+		if venue == "" {
+			venue = "all"
+		}
+		standings := leagueStandings(testMatchWeeks(test.matches...), 1, venue, test.last, test.gw, testSquads)
+		if len(standings) != 1 {
+			t.Errorf("%s: %d competitions in the standings, want 1", test.name, len(standings))
+			continue
+		}
+		got := []string{}
+		for _, s := range standings[1] {
+			got = append(got, s.Team)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: order %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLeagueStandingsRecord(t *testing.T) {
+	matchweeks := testMatchWeeks(testResult(1, 1, 2, 2, 0), testResult(2, 2, 1, 1, 1), testResult(3, 3, 1, 3, 1))
+	standings := leagueStandings(matchweeks, 0, "all", 0, 0, testSquads)
+	want := standing{SquadId: 1, Team: "Arsenal", Played: 3, Won: 1, Drawn: 1, Lost: 1, GoalsFor: 4, GoalsAgainst: 4, Points: 4, Form: []string{"W", "D", "L"}}
+	for _, s := range standings[1] {
+		if s.SquadId == 1 && !reflect.DeepEqual(s, want) {
+			t.Errorf("Arsenal's record is %+v, want %+v", s, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/helper"
+	"guysports/playerstats/pkg/types"
+	"path"
+)
+
+var (
+	requiredPlayerFields    = []string{"id", "first_name", "last_name", "squad_id", "cost", "positions", "stats"}
+	requiredMatchWeekFields = []string{"id", "status", "matches"}
+)
+
+// LoadReferenceData obtains the squads and competitions and keeps them in the globals by id
+func LoadReferenceData(globals *Globals) error {
+	data, err := helper.GetJSON(globals.SquadSource)
+	if err != nil {
+		return err
+	}
+	squads := []types.Squad{}
+	if err := helper.DecodeFeed(path.Base(globals.SquadSource), data, &squads, []string{"id", "full_name"}, nil); err != nil {
+		return err
+	}
+
+	data, err = helper.GetJSON(globals.CompetitionSource)
+	if err != nil {
+		return err
+	}
+	competitions := []types.Competition{}
+	if err := helper.DecodeFeed(path.Base(globals.CompetitionSource), data, &competitions, []string{"id", "name"}, nil); err != nil {
+		return err
+	}
+
+	globals.CompetitionMap = map[int]types.Competition{}
+	for _, competition := range competitions {
+		globals.CompetitionMap[competition.ID] = competition
+	}
+	globals.SquadMap = map[int]types.Squad{}
+	for _, squad := range squads {
+		globals.SquadMap[squad.ID] = squad
+	}
+	return nil
+}
+
+// loadPlayers obtains the player data
+func loadPlayers(globals *Globals) ([]types.Player, error) {
+	data, err := helper.GetJSON(globals.Source)
+	if err != nil {
+		return nil, err
+	}
+	return decodePlayers(globals, path.Base(globals.Source), data)
+}
+
+// decodePlayers decodes and validates player data, skipping any player that cannot be displayed
+func decodePlayers(globals *Globals, source string, data []byte) ([]types.Player, error) {
+	players := []types.Player{}
+	err := helper.DecodeFeed(source, data, &players, requiredPlayerFields, func(record interface{}) error {
+		player := record.(*types.Player)
+		if len(player.Positions) == 0 {
+			return fmt.Errorf("player %d %s has no positions", player.Id, playerName(*player))
+		}
+		if _, ok := types.Position[player.Positions[0]]; !ok {
+			return fmt.Errorf("player %d %s has unknown position %d", player.Id, playerName(*player), player.Positions[0])
+		}
+		if _, ok := globals.SquadMap[player.SquadId]; !ok {
+			return fmt.Errorf("player %d %s has unknown squad %d", player.Id, playerName(*player), player.SquadId)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	decoratePlayers(players, globals.SquadMap)
+	return players, nil
+}
+
+// decoratePlayers adds the team, position and display cost to each player
+func decoratePlayers(players []types.Player, squads map[int]types.Squad) {
+	abbreviations := squadAbbreviations(squads)
+	for i, player := range players {
+		squad := squads[player.SquadId]
+		players[i].Team = squad.Name
+		players[i].TeamShort = squadShortName(squad)
+		players[i].TeamAbbr = abbreviations[player.SquadId]
+		if len(player.Positions) > 0 {
+			players[i].Job = types.Position[player.Positions[0]]
+		}
+		players[i].CostDisp = fmt.Sprintf("&pound;%.2fm", float64(player.Cost)/1000000)
+	}
+}
+
+// loadMatchWeeks obtains the game week data
+func loadMatchWeeks(globals *Globals) ([]types.MatchWeek, error) {
+	data, err := helper.GetJSON(globals.MatchesSource)
+	if err != nil {
+		return nil, err
+	}
+	matchweeks := []types.MatchWeek{}
+	err = helper.DecodeFeed(path.Base(globals.MatchesSource), data, &matchweeks, requiredMatchWeekFields, func(record interface{}) error {
+		week := record.(*types.MatchWeek)
+		if week.Id <= 0 {
+			return fmt.Errorf("invalid game week id %d", week.Id)
+		}
+		for _, match := range week.MatchesInWeek {
+			if match.Id == 0 || match.HomeSquadId == 0 || match.AwaySquadId == 0 {
+				return fmt.Errorf("game week %d has a match without an id or squads", week.Id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matchweeks, nil
+}
+
+// loadPlayerData loads the players and game weeks and works out each player's appearances, projected
+// points over the next weeks and blank and double game weeks
+func loadPlayerData(globals *Globals, weeks int) ([]types.Player, []types.MatchWeek, error) {
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return nil, nil, err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachAppearances(players, matchweeks, path.Base(globals.Source))
+	applyProjections(players, newProjector(matchweeks, weeks, globals.SquadMap))
+	attachGameweeks(players, newGameweekCalendar(matchweeks), upcomingGameweeks(matchweeks, 0), globals.CompetitionMap)
+	return players, matchweeks, nil
+}
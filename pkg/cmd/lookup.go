@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// letterFolds maps the letters that do not decompose into a base letter and accent to their
+// unaccented equivalents
+var letterFolds = map[rune]string{
+	'æ': "ae", 'đ': "d", 'ð': "d", 'ı': "i", 'ł': "l", 'ø': "o", 'œ': "oe", 'ß': "ss", 'þ': "th",
+}
+
+// foldName lower cases a name, removes accents and collapses whitespace so names can be compared loosely
+func foldName(name string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), strings.ToLower(name))
+	if err != nil {
+		stripped = strings.ToLower(name)
+	}
+	var b strings.Builder
+	for _, r := range stripped {
+		if folded, ok := letterFolds[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// lookupPlayers returns the players matching a name. An id or an exact first, last or full name
+// match is preferred, otherwise any player whose full name contains the name is returned
+func lookupPlayers(players []types.Player, name string) []types.Player {
+	if id, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+		for _, player := range players {
+			if player.Id == id {
+				return []types.Player{player}
+			}
+		}
+	}
+
+	query := foldName(name)
+	if query == "" {
+		return nil
+	}
+	exact := []types.Player{}
+	partial := []types.Player{}
+	for _, player := range players {
+		full := foldName(playerName(player))
+		switch {
+		case full == query || foldName(player.LastName) == query || foldName(player.FirstName) == query:
+			exact = append(exact, player)
+		case strings.Contains(full, query):
+			partial = append(partial, player)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// resolvePlayer finds the single player matching a name, listing the candidates when the name is
+// ambiguous and suggesting close names when nothing matches
+func resolvePlayer(players []types.Player, name string) (types.Player, error) {
+	matches := lookupPlayers(players, name)
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		suggestions := suggestPlayers(players, name, 5)
+		if len(suggestions) == 0 {
+			return types.Player{}, fmt.Errorf("no player found matching %q", name)
+		}
+		names := []string{}
+		for _, player := range suggestions {
+			names = append(names, playerName(player))
+		}
+		return types.Player{}, fmt.Errorf("no player found matching %q, did you mean %s?", name, strings.Join(names, ", "))
+	}
+
+	lines := []string{fmt.Sprintf("%q matches %d players, use the id to choose one:", name, len(matches))}
+	for i := range matches {
+		lines = append(lines, fmt.Sprintf("  %6d  %-25s %-20s %-12s %s",
+			matches[i].Id,
+			playerName(matches[i]),
+			playerFields["team"].value(&matches[i]),
+			playerFields["position"].value(&matches[i]),
+			formatCost(matches[i].Cost)))
+	}
+	return types.Player{}, fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// suggestPlayers returns up to n players whose names are within a small edit distance of the name
+func suggestPlayers(players []types.Player, name string, n int) []types.Player {
+	query := foldName(name)
+	maxDistance := len(query)/3 + 1
+	type suggestion struct {
+		player   types.Player
+		distance int
+	}
+	suggestions := []suggestion{}
+	for _, player := range players {
+		best := -1
+		for _, candidate := range []string{player.LastName, player.FirstName, playerName(player)} {
+			distance := levenshtein(query, foldName(candidate))
+			if best < 0 || distance < best {
+				best = distance
+			}
+		}
+		if best <= maxDistance {
+			suggestions = append(suggestions, suggestion{player, best})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+	result := []types.Player{}
+	for i := 0; i < len(suggestions) && i < n; i++ {
+		result = append(result, suggestions[i].player)
+	}
+	return result
+}
+
+func levenshtein(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j] + 1
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
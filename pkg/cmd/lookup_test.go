@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFoldName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Bruno Fernandes", "bruno fernandes"},
+		{"  Kevin   De Bruyne ", "kevin de bruyne"},
+		{"Rúben Dias", "ruben dias"},
+		{"Ștefan Țîrlea", "stefan tirlea"},
+		{"Şener Ţurcan", "sener turcan"},
+		{"Rube\u0301n Di\u0301as", "ruben dias"},
+		{"Martin Ødegaard", "martin odegaard"},
+		{"Łukasz Fabiański", "lukasz fabianski"},
+		{"Matthias Ginter Straße", "matthias ginter strasse"},
+		{"Nicolás Tagliafico", "nicolas tagliafico"},
+	}
+	for _, test := range tests {
+		if got := foldName(test.name); got != test.want {
+			t.Errorf("foldName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLookupPlayers(t *testing.T) {
+	players := []types.Player{
+		{Id: 1, FirstName: "Rúben", LastName: "Dias"},
+		{Id: 2, FirstName: "Luis", LastName: "Díaz"},
+		{Id: 3, FirstName: "Martin", LastName: "Ødegaard"},
+		{Id: 4, FirstName: "Ben", LastName: "White"},
+		{Id: 5, FirstName: "Ben", LastName: "Mee"},
+		{Id: 6, FirstName: "Benjamin", LastName: "Mendy"},
+	}
+	tests := []struct {
+		name string
+		want []int
+	}{
+		{"3", []int{3}},
+		{"ruben dias", []int{1}},
+		{"Rubén", []int{1}},
+		{"Rube\u0301n", []int{1}},
+		{"diaz", []int{2}},
+		{"odegaard", []int{3}},
+		{"ben", []int{4, 5}},
+		{"benj", []int{6}},
+		{"dia", []int{1, 2}},
+		{"mend", []int{6}},
+		{"haaland", []int{}},
+		{" ", []int{}},
+	}
+	for _, test := range tests {
+		got := []int{}
+		for _, player := range lookupPlayers(players, test.name) {
+			got = append(got, player.Id)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("lookupPlayers(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	errors := []struct {
+		name string
+		want []string
+	}{
+		{"ben", []string{`"ben" matches 2 players`, "Ben White", "Ben Mee"}},
+		{"odegard", []string{`no player found matching "odegard", did you mean Martin Ødegaard?`}},
+		{"dais", []string{"did you mean Rúben Dias, Luis Díaz?"}},
+		{"haaland", []string{`no player found matching "haaland"`}},
+	}
+	for _, test := range errors {
+		_, err := resolvePlayer(players, test.name)
+		if err == nil {
+			t.Errorf("resolvePlayer(%q) found a player, want an error", test.name)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("resolvePlayer(%q) error %q, want it to contain %q", test.name, err, want)
+			}
+		}
+	}
+}
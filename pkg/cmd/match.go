@@ -1,64 +1,114 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"guysports/playerstats/pkg/helper"
 	"guysports/playerstats/pkg/types"
+	"os"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
 )
 
 type (
 	Match struct {
-		Gw     int      `help:"List the fixtures for the game week"`
-		Filter []string `help:"apply criteria to fixtures to display, with separated filter=value,filter=value list"`
+		Gw      int      `help:"List the fixtures for the game week, 0 for all game weeks"`
+		Filter  []string `help:"apply criteria to fixtures to display, with separated filter=value,filter=value list, supported are team, competition, status, from, to and gw (e.g. gw=3-5)"`
+		Details bool     `help:"show the goals, assists and cards of each fixture, in table and json output"`
+		Output  string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
 	}
 )
 
 func (m *Match) Run(globals *Globals) error {
 	// Obtain the game week data
-	data, err := helper.GetJSON(globals.MatchesSource)
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+
+	matchFilter, err := parseMatchFilters(m.Filter, newTeamIndex(globals.SquadMap, globals.TeamAliases), globals.CompetitionMap)
 	if err != nil {
 		return err
 	}
-	matchweeks := []types.MatchWeek{}
-	_ = json.Unmarshal(data, &matchweeks)
+	if m.Gw != 0 {
+		if m.Gw < 1 || m.Gw > len(matchweeks) {
+			return fmt.Errorf("game week %d is out of range, there are %d game weeks", m.Gw, len(matchweeks))
+		}
+		matchFilter.gwFrom = m.Gw
+		matchFilter.gwTo = m.Gw
+	}
+
+	// Select the fixtures that meet the filter in date order
+	fixtures := []types.Match{}
+	for _, week := range matchweeks {
+		for _, fixture := range week.MatchesInWeek {
+			if matchFilter.matches(fixture) {
+				fixtures = append(fixtures, fixture)
+			}
+		}
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool {
+		return fixtures[i].Date < fixtures[j].Date
+	})
 
-	gwFixtures := matchweeks[m.Gw-1]
-	for _, fixture := range gwFixtures.MatchesInWeek {
-		fmt.Printf("%s %d v %d %s\n", globals.SquadMap[fixture.HomeSquadId].Name, fixture.HomeScore, fixture.AwayScore, globals.SquadMap[fixture.AwaySquadId].Name)
+	// Player names are only needed for the match details
+	playerMap := map[int]types.Player{}
+	if m.Details {
+		players, err := loadPlayers(globals)
+		if err != nil {
+			return err
+		}
+		for _, player := range players {
+			playerMap[player.Id] = player
+		}
 	}
 
-	//homeForm, awayForm := loadForm(matchweeks)
+	renderedMatches := []types.RenderedMatch{}
+	for _, fixture := range fixtures {
+		rendered := renderMatch(fixture, globals.CompetitionMap, globals.SquadMap)
+		if m.Details {
+			rendered.Events = renderEvents(fixture, playerMap)
+		}
+		renderedMatches = append(renderedMatches, rendered)
+	}
+	if m.Output != "table" {
+		return writeMatches(m.Output, renderedMatches)
+	}
 
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"GW", "Date", "Kickoff", "Competition", "Fixture", "Result", "Venue", "Status"})
+	for _, match := range renderedMatches {
+		t.AppendRow(table.Row{match.Gw, match.Date, match.Kickoff, match.Competition, match.Fixture, match.Result, match.Venue, match.Status})
+	}
+	t.Render()
+
+	if m.Details {
+		for _, match := range renderedMatches {
+			if len(match.Events) > 0 {
+				renderTimeline(match)
+			}
+		}
+	}
 	return nil
 }
 
-func loadForm(mw []types.MatchWeek) (map[int][]types.Match, map[int][]types.Match) {
+// loadForm splits the completed matches up to and including the cutoff game week, 0 for all,
+// into the matches played at home and away by each squad
+func loadForm(mw []types.MatchWeek, cutoff int) (map[int][]types.Match, map[int][]types.Match) {
 	homeForm := map[int][]types.Match{}
 	awayForm := map[int][]types.Match{}
 	for _, week := range mw {
-		if week.Status != "complete" {
+		if cutoff > 0 && week.Id > cutoff {
 			continue
 		}
 
-		// For each complete week, extract each match and add the matches to the maps based on a squadID key
+		// For each complete match, add the matches to the maps based on a squadID key
 		for _, match := range week.MatchesInWeek {
-			_, ok := homeForm[match.HomeSquadId]
-			if !ok {
-				homeForm[match.HomeSquadId] = []types.Match{
-					match,
-				}
-			} else {
-				homeForm[match.HomeSquadId] = append(homeForm[match.HomeSquadId], match)
-			}
-			_, ok = awayForm[match.AwaySquadId]
-			if !ok {
-				awayForm[match.AwaySquadId] = []types.Match{
-					match,
-				}
-			} else {
-				awayForm[match.AwaySquadId] = append(awayForm[match.AwaySquadId], match)
+			if match.Status != "complete" {
+				continue
 			}
+			homeForm[match.HomeSquadId] = append(homeForm[match.HomeSquadId], match)
+			awayForm[match.AwaySquadId] = append(awayForm[match.AwaySquadId], match)
 		}
 	}
 	return homeForm, awayForm
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matchFilter selects fixtures, where each set criteria must be met and repeated criteria of the same kind are alternatives
+type matchFilter struct {
+	squads       map[int]bool
+	competitions map[int]bool
+	statuses     map[string]bool
+	from         string
+	to           string
+	gwFrom       int
+	gwTo         int
+}
+
+func parseMatchFilters(filters []string, teams *teamIndex, competitions map[int]types.Competition) (*matchFilter, error) {
+	matchFilter := matchFilter{
+		squads:       map[int]bool{},
+		competitions: map[int]bool{},
+		statuses:     map[string]bool{},
+	}
+	for _, filterValuePair := range filters {
+		pair := strings.SplitN(filterValuePair, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid filter %q, expected filter=value", filterValuePair)
+		}
+		value := pair[1]
+		switch pair[0] {
+		case "team":
+			id, ok := teams.lookup(value)
+			if !ok {
+				return nil, fmt.Errorf("unknown team %q", value)
+			}
+			matchFilter.squads[id] = true
+		case "competition":
+			id, ok := lookupCompetition(competitions, value)
+			if !ok {
+				return nil, fmt.Errorf("unknown competition %q", value)
+			}
+			matchFilter.competitions[id] = true
+		case "status":
+			switch value {
+			case "complete", "scheduled", "playing":
+				matchFilter.statuses[value] = true
+			default:
+				return nil, fmt.Errorf("unknown status %q, supported are complete, scheduled and playing", value)
+			}
+		case "from", "to":
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return nil, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", pair[0], value)
+			}
+			if pair[0] == "from" {
+				matchFilter.from = value
+			} else {
+				matchFilter.to = value
+			}
+		case "gw":
+			from, to, err := parseRange(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid game week range %q: %v", value, err)
+			}
+			matchFilter.gwFrom = from
+			matchFilter.gwTo = to
+		default:
+			return nil, fmt.Errorf("unknown filter %q, supported are team, competition, status, from, to and gw", pair[0])
+		}
+	}
+	return &matchFilter, nil
+}
+
+// parseRange parses a single number or an inclusive range such as 3-5
+func parseRange(value string) (int, int, error) {
+	parts := strings.SplitN(value, "-", 2)
+	from, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("end is before start")
+	}
+	return from, to, nil
+}
+
+// lookupCompetition finds a competition by id, code or name
+func lookupCompetition(competitions map[int]types.Competition, value string) (int, bool) {
+	for id, competition := range competitions {
+		if strconv.Itoa(id) == value || strings.EqualFold(competition.Code, value) || strings.EqualFold(competition.Name, value) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (f *matchFilter) matches(match types.Match) bool {
+	if len(f.squads) > 0 && !f.squads[match.HomeSquadId] && !f.squads[match.AwaySquadId] {
+		return false
+	}
+	if len(f.competitions) > 0 && !f.competitions[match.CompetitionId] {
+		return false
+	}
+	if len(f.statuses) > 0 && !f.statuses[match.Status] {
+		return false
+	}
+	date := strings.Split(match.Date, "T")[0]
+	if f.from != "" && date < f.from {
+		return false
+	}
+	if f.to != "" && date > f.to {
+		return false
+	}
+	if f.gwFrom > 0 && (match.Gw < f.gwFrom || match.Gw > f.gwTo) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/filter"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type (
+	Optimize struct {
+		Objective   string   `help:"numeric player field to maximise, e.g. total_points, last_5_avg or projected" default:"total_points"`
+		Weeks       int      `help:"number of upcoming game weeks to project points over for the projected objective" default:"3"`
+		Budget      float64  `help:"total cost of the squad in millions" default:"100"`
+		Size        int      `help:"squad size, 15 picks a starting eleven and bench, 11 only a starting eleven" enum:"11,15" default:"15"`
+		MaxPerTeam  int      `help:"maximum number of players from one team, 0 for no limit" default:"3"`
+		Quotas      []string `help:"number of players in each position for a 15 player squad" default:"goalkeeper=2,defender=5,midfielder=5,forward=3"`
+		Formations  []string `help:"valid starting formations as defenders-midfielders-forwards" default:"3-4-3,3-5-2,4-3-3,4-4-2,4-5-1,5-3-2,5-4-1"`
+		BenchWeight float64  `help:"share of a substitute's objective value counted towards the squad, from 0 to 1" default:"0.1"`
+		Where       string   `help:"filter expression to select the players that can be picked, as for display --where"`
+		Output      string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// selectedPlayer is a player in the optimized squad
+	selectedPlayer struct {
+		Id       int     `json:"id"`
+		Name     string  `json:"name"`
+		Position string  `json:"position"`
+		Team     string  `json:"team"`
+		Cost     float64 `json:"cost"`
+		Value    float64 `json:"value"`
+	}
+
+	// optimizedSquad is the optimized squad in the form used for output
+	optimizedSquad struct {
+		Objective   string           `json:"objective"`
+		Formation   string           `json:"formation"`
+		Cost        float64          `json:"cost"`
+		Value       float64          `json:"value"`
+		Captain     string           `json:"captain"`
+		ViceCaptain string           `json:"vice_captain"`
+		Starters    []selectedPlayer `json:"starters"`
+		Bench       []selectedPlayer `json:"bench"`
+		captainId   int
+		viceId      int
+	}
+)
+
+func (o *Optimize) Run(globals *Globals) error {
+	if o.Budget < 0 {
+		return fmt.Errorf("invalid budget £%.2fm, it cannot be negative", o.Budget)
+	}
+	// The bound that prunes the search only holds while a substitute counts for no more than a starter
+	if o.BenchWeight < 0 || o.BenchWeight > 1 {
+		return fmt.Errorf("invalid bench weight %g, it must be from 0 to 1", o.BenchWeight)
+	}
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+	applyProjections(players, newProjector(matchweeks, o.Weeks, globals.SquadMap))
+	attachGameweeks(players, newGameweekCalendar(matchweeks), upcomingGameweeks(matchweeks, 0), globals.CompetitionMap)
+	objective, err := playerObjective(o.Objective)
+	if err != nil {
+		return err
+	}
+	formations, err := parseFormations(o.Formations)
+	if err != nil {
+		return err
+	}
+	quotas, err := parseQuotas(o.Quotas)
+	if err != nil {
+		return err
+	}
+	teams := newTeamIndex(globals.SquadMap, globals.TeamAliases)
+	expression, err := parseFilters(nil, o.Where, teams)
+	if err != nil {
+		return err
+	}
+
+	candidates := []candidate{}
+	for _, player := range players {
+		if expression != nil && !expression.Match(playerRecord(&player)) {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			player:   player,
+			position: player.Positions[0],
+			value:    objective(&player),
+		})
+	}
+
+	selection, ok := solveSquad(candidates, squadConstraints{
+		budget:       int(math.Round(o.Budget * 1e6)),
+		quotas:       quotas,
+		formations:   formations,
+		maxPerSquad:  o.MaxPerTeam,
+		benchWeight:  o.BenchWeight,
+		startersOnly: o.Size == 11,
+	})
+	if !ok {
+		return fmt.Errorf("no squad of %d players meets the budget of £%.2fm and the team and formation limits", o.Size, o.Budget)
+	}
+	squad := describeSelection(selection, o.Objective)
+
+	switch o.Output {
+	case "table":
+		renderOptimizedSquad(squad)
+		return nil
+	case "json":
+		return writeJSON(squad)
+	}
+	records := [][]string{}
+	for i, player := range squad.Starters {
+		records = append(records, selectedRecord("starter", i+1, player, squad))
+	}
+	for i, player := range squad.Bench {
+		records = append(records, selectedRecord("bench", i+1, player, squad))
+	}
+	return writeRecords(o.Output, []string{"role", "order", "id", "name", "position", "team", "cost", "value", "captain"}, records)
+}
+
+// playerObjective resolves the objective to a numeric player field
+func playerObjective(name string) (func(player *types.Player) float64, error) {
+	for field, definition := range playerFields {
+		if filter.Normalize(field) != filter.Normalize(name) {
+			continue
+		}
+		if definition.kind != filter.Number {
+			return nil, fmt.Errorf("objective %q is not a numeric field", name)
+		}
+		value := definition.value
+		return func(player *types.Player) float64 {
+			return value(player).(float64)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown objective %q\navailable fields: %s", name, strings.Join(playerFieldNames(), ", "))
+}
+
+// describeSelection converts the optimizer result for output, choosing the starters with the highest
+// objective values as captain and vice captain
+func describeSelection(selection squadSelection, objective string) optimizedSquad {
+	squad := optimizedSquad{
+		Objective: objective,
+		Formation: selection.formation,
+		Cost:      float64(selection.cost) / 1000000,
+		Value:     selection.value,
+		Starters:  []selectedPlayer{},
+		Bench:     []selectedPlayer{},
+	}
+	captain, vice := -1, -1
+	for i, c := range selection.starters {
+		if captain < 0 || c.value > selection.starters[captain].value {
+			captain, vice = i, captain
+		} else if vice < 0 || c.value > selection.starters[vice].value {
+			vice = i
+		}
+		squad.Starters = append(squad.Starters, newSelectedPlayer(c))
+	}
+	if captain >= 0 {
+		squad.Captain = squad.Starters[captain].Name
+		squad.captainId = squad.Starters[captain].Id
+	}
+	if vice >= 0 {
+		squad.ViceCaptain = squad.Starters[vice].Name
+		squad.viceId = squad.Starters[vice].Id
+	}
+	for _, c := range selection.bench {
+		squad.Bench = append(squad.Bench, newSelectedPlayer(c))
+	}
+	return squad
+}
+
+func newSelectedPlayer(c candidate) selectedPlayer {
+	return selectedPlayer{
+		Id:       c.player.Id,
+		Name:     playerName(c.player),
+		Position: c.player.Job,
+		Team:     c.player.Team,
+		Cost:     float64(c.player.Cost) / 1000000,
+		Value:    c.value,
+	}
+}
+
+func selectedRecord(role string, order int, player selectedPlayer, squad optimizedSquad) []string {
+	captain := ""
+	if role == "starter" {
+		switch player.Id {
+		case squad.captainId:
+			captain = "C"
+		case squad.viceId:
+			captain = "V"
+		}
+	}
+	return []string{
+		role,
+		fmt.Sprintf("%d", order),
+		fmt.Sprintf("%d", player.Id),
+		player.Name,
+		player.Position,
+		player.Team,
+		fmt.Sprintf("%.2f", player.Cost),
+		formatFieldValue("", player.Value),
+		captain,
+	}
+}
+
+func renderOptimizedSquad(squad optimizedSquad) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(fmt.Sprintf("Best squad by %s, formation %s", squad.Objective, squad.Formation))
+	t.AppendHeader(table.Row{"", "Position", "Player", "Team", "Cost", "Value"})
+	for i, player := range squad.Starters {
+		record := selectedRecord("starter", i+1, player, squad)
+		t.AppendRow(table.Row{record[8], player.Position, player.Name, player.Team, fmt.Sprintf("£%.2fm", player.Cost), record[7]})
+	}
+	if len(squad.Bench) > 0 {
+		t.AppendSeparator()
+		for i, player := range squad.Bench {
+			t.AppendRow(table.Row{fmt.Sprintf("Sub %d", i+1), player.Position, player.Name, player.Team, fmt.Sprintf("£%.2fm", player.Cost), formatFieldValue("", player.Value)})
+		}
+	}
+	t.AppendSeparator()
+	t.AppendRow(table.Row{"", "", "Total", "", fmt.Sprintf("£%.2fm", squad.Cost), formatFieldValue("", squad.Value)})
+	t.Render()
+}
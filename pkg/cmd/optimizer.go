@@ -0,0 +1,495 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// formation is the number of starting players in each position, including the goalkeeper
+	formation struct {
+		name   string
+		counts map[int]int
+	}
+
+	// squadConstraints are the rules a selected squad has to meet
+	squadConstraints struct {
+		budget      int
+		quotas      map[int]int
+		formations  []formation
+		maxPerSquad int
+		// squadLimits override maxPerSquad for individual squads
+		squadLimits  map[int]int
+		benchWeight  float64
+		startersOnly bool
+	}
+
+	// candidate is a player that can be selected along with the objective value of selecting them
+	candidate struct {
+		player   types.Player
+		position int
+		value    float64
+	}
+
+	// squadSelection is the result of the optimizer
+	squadSelection struct {
+		formation string
+		starters  []candidate
+		bench     []candidate
+		value     float64
+		cost      int
+	}
+)
+
+// parseFormations parses formations written as defenders-midfielders-forwards, e.g. 4-4-2
+func parseFormations(values []string) ([]formation, error) {
+	formations := []formation{}
+	for _, value := range values {
+		parts := strings.Split(strings.TrimSpace(value), "-")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid formation %q, expected defenders-midfielders-forwards, e.g. 4-4-2", value)
+		}
+		counts := map[int]int{1: 1}
+		total := 1
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid formation %q, expected defenders-midfielders-forwards, e.g. 4-4-2", value)
+			}
+			counts[i+2] = n
+			total += n
+		}
+		if total != 11 {
+			return nil, fmt.Errorf("formation %q has %d players, expected 11", value, total)
+		}
+		formations = append(formations, formation{name: strings.TrimSpace(value), counts: counts})
+	}
+	if len(formations) == 0 {
+		return nil, fmt.Errorf("at least one formation is required")
+	}
+	return formations, nil
+}
+
+// parseQuotas parses position=count pairs giving the number of players in the squad for each position
+func parseQuotas(values []string) (map[int]int, error) {
+	positions := map[string]int{}
+	for id, name := range types.Position {
+		positions[name] = id
+	}
+	quotas := map[int]int{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid quota %q, expected position=count", value)
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(parts[0]))]
+		if !ok {
+			return nil, fmt.Errorf("unknown position %q in quota %q", parts[0], value)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid count in quota %q", value)
+		}
+		quotas[position] = n
+	}
+	return quotas, nil
+}
+
+// squadLimit returns the most players that can be picked from a squad, or -1 if there is no limit
+func (c squadConstraints) squadLimit(squadId int) int {
+	if limit, ok := c.squadLimits[squadId]; ok {
+		return limit
+	}
+	if c.maxPerSquad > 0 {
+		return c.maxPerSquad
+	}
+	return -1
+}
+
+// solveSquad finds the selection with the highest objective value, where starters count in full and
+// the bench counts at the bench weight, by branch and bound over the candidates for each formation.
+// It reports false if no selection meets the constraints
+func solveSquad(candidates []candidate, constraints squadConstraints) (squadSelection, bool) {
+	best := squadSelection{value: math.Inf(-1)}
+	found := false
+
+	// The squad limits alone can rule out a selection, -1 is no limit
+	squads := map[int]bool{}
+	for _, c := range candidates {
+		squads[c.player.SquadId] = true
+	}
+	capacity := 0
+	for squadId := range squads {
+		limit := constraints.squadLimit(squadId)
+		if limit < 0 {
+			capacity = -1
+			break
+		}
+		capacity += limit
+	}
+
+	for _, f := range constraints.formations {
+		starterSlots := map[int]int{}
+		benchSlots := map[int]int{}
+		valid := true
+		for position := range types.Position {
+			starterSlots[position] = f.counts[position]
+			if constraints.startersOnly {
+				continue
+			}
+			benchSlots[position] = constraints.quotas[position] - f.counts[position]
+			if benchSlots[position] < 0 {
+				valid = false
+			}
+		}
+		size := 0
+		for position := range types.Position {
+			size += starterSlots[position] + benchSlots[position]
+		}
+		if !valid || (capacity >= 0 && capacity < size) {
+			continue
+		}
+		search := newSquadSearch(pruneDominated(candidates, starterSlots, benchSlots, constraints), starterSlots, benchSlots, constraints)
+		search.best = best.value
+		search.run(0, 0, constraints.budget)
+		if search.found {
+			found = true
+			best = search.selection()
+			best.formation = f.name
+		}
+	}
+	return best, found
+}
+
+// pruneDominated drops candidates that can never be needed, either because their squad is full or
+// because enough players in the same position from enough different squads are worth at least as
+// much for no more cost. Players from a full squad can never stand in for another, so they are not
+// counted as dominating anyone
+func pruneDominated(candidates []candidate, starterSlots map[int]int, benchSlots map[int]int, constraints squadConstraints) []candidate {
+	size := 0
+	for position := range types.Position {
+		size += starterSlots[position] + benchSlots[position]
+	}
+	eligible := []candidate{}
+	for _, c := range candidates {
+		if starterSlots[c.position]+benchSlots[c.position] > 0 && constraints.squadLimit(c.player.SquadId) != 0 {
+			eligible = append(eligible, c)
+		}
+	}
+
+	// The number of squads that could be full in a selection, filling the smallest limits first
+	limits := []int{}
+	seen := map[int]bool{}
+	for _, c := range eligible {
+		if seen[c.player.SquadId] {
+			continue
+		}
+		seen[c.player.SquadId] = true
+		if limit := constraints.squadLimit(c.player.SquadId); limit > 0 {
+			limits = append(limits, limit)
+		}
+	}
+	sort.Ints(limits)
+	cappedSquads, used := 0, 0
+	for _, limit := range limits {
+		if used+limit > size {
+			break
+		}
+		used += limit
+		cappedSquads++
+	}
+
+	kept := []candidate{}
+	for i, c := range eligible {
+		slots := starterSlots[c.position] + benchSlots[c.position]
+		dominators := map[int]bool{}
+		for j, other := range eligible {
+			if i == j || other.position != c.position || other.value < c.value || other.player.Cost > c.player.Cost {
+				continue
+			}
+			if other.value == c.value && other.player.Cost == c.player.Cost && j > i {
+				continue
+			}
+			dominators[other.player.SquadId] = true
+		}
+		if len(dominators) < slots+cappedSquads {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// squadSearch is the state of the branch and bound search for a single formation
+type squadSearch struct {
+	candidates   []candidate
+	constraints  squadConstraints
+	positions    []int
+	starterSlots map[int]int
+	benchSlots   map[int]int
+	// byPosition holds the values of the candidates in each position as prefix sums, in search order
+	byPosition map[int][]float64
+	// decided counts the candidates in each position before each search index
+	decided []map[int]int
+	// cheapest holds the prefix sums of the lowest costs in each position
+	cheapest map[int][]int
+	// lambda prices the budget in the relaxed bound, where each candidate is worth its value less
+	// lambda times its cost and the unspent budget is worth lambda per million
+	lambda float64
+	// starterTop and benchTop hold the highest relaxed values of the candidates in each position from
+	// each search index on, enough to fill the starter and bench slots
+	starterTop map[int][][]float64
+	benchTop   map[int][][]float64
+	squadCount map[int]int
+	role       []int
+	bestRole   []int
+	best       float64
+	found      bool
+}
+
+const (
+	unselected = iota
+	starter
+	substitute
+)
+
+func newSquadSearch(candidates []candidate, starterSlots map[int]int, benchSlots map[int]int, constraints squadConstraints) *squadSearch {
+	sorted := append([]candidate{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].value != sorted[j].value {
+			return sorted[i].value > sorted[j].value
+		}
+		return sorted[i].player.Cost < sorted[j].player.Cost
+	})
+	s := &squadSearch{
+		candidates:   sorted,
+		constraints:  constraints,
+		starterSlots: map[int]int{},
+		benchSlots:   map[int]int{},
+		byPosition:   map[int][]float64{},
+		decided:      []map[int]int{},
+		cheapest:     map[int][]int{},
+		squadCount:   map[int]int{},
+		role:         make([]int, len(sorted)),
+	}
+	for position := range types.Position {
+		s.positions = append(s.positions, position)
+		s.starterSlots[position] = starterSlots[position]
+		s.benchSlots[position] = benchSlots[position]
+		s.byPosition[position] = []float64{0}
+	}
+	sort.Ints(s.positions)
+	costs := map[int][]int{}
+	counts := map[int]int{}
+	for _, c := range sorted {
+		s.decided = append(s.decided, copyCounts(counts))
+		counts[c.position]++
+		values := s.byPosition[c.position]
+		s.byPosition[c.position] = append(values, values[len(values)-1]+c.value)
+		costs[c.position] = append(costs[c.position], c.player.Cost)
+	}
+	s.decided = append(s.decided, counts)
+	for _, position := range s.positions {
+		sort.Ints(costs[position])
+		s.cheapest[position] = []int{0}
+		for _, cost := range costs[position] {
+			s.cheapest[position] = append(s.cheapest[position], s.cheapest[position][len(s.cheapest[position])-1]+cost)
+		}
+	}
+
+	// Pick the budget price that gives the tightest bound for the whole search
+	low, high := 0.0, 0.0
+	for _, c := range sorted {
+		if c.player.Cost > 0 && c.value/millions(c.player.Cost) > high {
+			high = c.value / millions(c.player.Cost)
+		}
+	}
+	for i := 0; i < 60; i++ {
+		a, b := low+(high-low)/3, high-(high-low)/3
+		if s.rootBound(a) < s.rootBound(b) {
+			high = b
+		} else {
+			low = a
+		}
+	}
+	s.setLambda((low + high) / 2)
+	return s
+}
+
+func copyCounts(counts map[int]int) map[int]int {
+	copied := map[int]int{}
+	for key, value := range counts {
+		copied[key] = value
+	}
+	return copied
+}
+
+func millions(cost int) float64 {
+	return float64(cost) / 1000000
+}
+
+// setLambda builds the tables of the highest relaxed values from each search index for a budget price
+func (s *squadSearch) setLambda(lambda float64) {
+	s.lambda = lambda
+	s.starterTop = map[int][][]float64{}
+	s.benchTop = map[int][][]float64{}
+	for _, position := range s.positions {
+		s.starterTop[position] = make([][]float64, len(s.candidates)+1)
+		s.benchTop[position] = make([][]float64, len(s.candidates)+1)
+	}
+	for i := len(s.candidates) - 1; i >= 0; i-- {
+		c := s.candidates[i]
+		for _, position := range s.positions {
+			s.starterTop[position][i] = s.starterTop[position][i+1]
+			s.benchTop[position][i] = s.benchTop[position][i+1]
+		}
+		cost := lambda * millions(c.player.Cost)
+		s.starterTop[c.position][i] = insertTop(s.starterTop[c.position][i+1], c.value-cost, s.starterSlots[c.position])
+		s.benchTop[c.position][i] = insertTop(s.benchTop[c.position][i+1], s.constraints.benchWeight*c.value-cost, s.benchSlots[c.position])
+	}
+}
+
+// insertTop returns a copy of the descending values with the value added, keeping at most n
+func insertTop(values []float64, value float64, n int) []float64 {
+	top := make([]float64, 0, n)
+	added := false
+	for _, v := range values {
+		if !added && value > v {
+			top = append(top, value)
+			added = true
+		}
+		top = append(top, v)
+	}
+	if !added {
+		top = append(top, value)
+	}
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// rootBound is the relaxed bound of the whole search for a budget price
+func (s *squadSearch) rootBound(lambda float64) float64 {
+	bound := lambda * millions(s.constraints.budget)
+	for _, position := range s.positions {
+		starters := []float64{}
+		bench := []float64{}
+		for _, c := range s.candidates {
+			if c.position != position {
+				continue
+			}
+			cost := lambda * millions(c.player.Cost)
+			starters = insertTop(starters, c.value-cost, s.starterSlots[position])
+			bench = insertTop(bench, s.constraints.benchWeight*c.value-cost, s.benchSlots[position])
+		}
+		for _, v := range append(starters, bench...) {
+			bound += v
+		}
+	}
+	return bound
+}
+
+// bound returns the most the selection could be worth from index on, ignoring squad limits, and
+// reports false if the remaining slots cannot be filled within the budget. It is the lower of the
+// best values ignoring the budget and the best relaxed values with the budget priced in
+func (s *squadSearch) bound(index int, value float64, budget int) (float64, bool) {
+	minCost := 0
+	plain := value
+	relaxed := value + s.lambda*millions(budget)
+	for _, position := range s.positions {
+		starters, bench := s.starterSlots[position], s.benchSlots[position]
+		values := s.byPosition[position]
+		from := s.decided[index][position]
+		if from+starters+bench >= len(values) {
+			return 0, false
+		}
+		plain += values[from+starters] - values[from]
+		plain += s.constraints.benchWeight * (values[from+starters+bench] - values[from+starters])
+		minCost += s.cheapest[position][starters+bench]
+		for _, v := range s.starterTop[position][index][:starters] {
+			relaxed += v
+		}
+		for _, v := range s.benchTop[position][index][:bench] {
+			relaxed += v
+		}
+	}
+	return math.Min(plain, relaxed), minCost <= budget
+}
+
+func (s *squadSearch) run(index int, value float64, budget int) {
+	remaining := 0
+	for _, position := range s.positions {
+		remaining += s.starterSlots[position] + s.benchSlots[position]
+	}
+	if remaining == 0 {
+		if value > s.best {
+			s.best = value
+			s.found = true
+			s.bestRole = append([]int{}, s.role...)
+		}
+		return
+	}
+	if index == len(s.candidates) {
+		return
+	}
+	limit, feasible := s.bound(index, value, budget)
+	if !feasible || limit <= s.best+1e-9 {
+		return
+	}
+
+	c := s.candidates[index]
+	affordable := c.player.Cost <= budget
+	squadLimit := s.constraints.squadLimit(c.player.SquadId)
+	squadOk := squadLimit < 0 || s.squadCount[c.player.SquadId] < squadLimit
+	if affordable && squadOk {
+		s.squadCount[c.player.SquadId]++
+		if s.starterSlots[c.position] > 0 {
+			s.starterSlots[c.position]--
+			s.role[index] = starter
+			s.run(index+1, value+c.value, budget-c.player.Cost)
+			s.starterSlots[c.position]++
+		}
+		if s.benchSlots[c.position] > 0 {
+			s.benchSlots[c.position]--
+			s.role[index] = substitute
+			s.run(index+1, value+s.constraints.benchWeight*c.value, budget-c.player.Cost)
+			s.benchSlots[c.position]++
+		}
+		s.squadCount[c.player.SquadId]--
+	}
+	s.role[index] = unselected
+	s.run(index+1, value, budget)
+}
+
+// selection returns the best selection found, with starters in position order and the bench
+// ordered goalkeeper first then by value
+func (s *squadSearch) selection() squadSelection {
+	selection := squadSelection{value: s.best}
+	for i, role := range s.bestRole {
+		c := s.candidates[i]
+		switch role {
+		case starter:
+			selection.starters = append(selection.starters, c)
+		case substitute:
+			selection.bench = append(selection.bench, c)
+		default:
+			continue
+		}
+		selection.cost += c.player.Cost
+	}
+	sort.SliceStable(selection.starters, func(i, j int) bool {
+		return selection.starters[i].position < selection.starters[j].position
+	})
+	sort.SliceStable(selection.bench, func(i, j int) bool {
+		a, b := selection.bench[i], selection.bench[j]
+		if (a.position == 1) != (b.position == 1) {
+			return a.position == 1
+		}
+		return a.value > b.value
+	})
+	return selection
+}
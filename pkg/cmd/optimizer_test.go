@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testCandidates returns a pool with count players in each position, spread over a few squads, with
+// random costs and values
+func testCandidates(random *rand.Rand, counts map[int]int) []candidate {
+	candidates := []candidate{}
+	id := 0
+	for position := 1; position <= len(types.Position); position++ {
+		for i := 0; i < counts[position]; i++ {
+			id++
+			candidates = append(candidates, candidate{
+				player:   types.Player{Id: id, SquadId: 1 + random.Intn(3), Cost: (40 + random.Intn(40)) * 100000, Positions: []int{position}},
+				position: position,
+				value:    float64(random.Intn(200)) / 10,
+			})
+		}
+	}
+	return candidates
+}
+
+// bruteForceSquad tries every squad that meets the quotas, or every starting eleven when the
+// constraints are for starters only, returning the best value and whether any squad was valid
+func bruteForceSquad(candidates []candidate, constraints squadConstraints) (float64, bool) {
+	byPosition := map[int][]candidate{}
+	for _, c := range candidates {
+		byPosition[c.position] = append(byPosition[c.position], c)
+	}
+	best, found := math.Inf(-1), false
+	for _, f := range constraints.formations {
+		sizes := f.counts
+		if !constraints.startersOnly {
+			sizes = constraints.quotas
+		}
+		var choose func(position int, picked []candidate)
+		choose = func(position int, picked []candidate) {
+			if position > len(types.Position) {
+				if value, ok := scoreSquad(picked, f, constraints); ok && value > best {
+					best, found = value, true
+				}
+				return
+			}
+			for _, group := range subsets(byPosition[position], sizes[position]) {
+				choose(position+1, append(append([]candidate{}, picked...), group...))
+			}
+		}
+		choose(1, nil)
+	}
+	return best, found
+}
+
+// scoreSquad checks a squad against the budget and squad limits, then starts the most valuable
+// players in each position of the formation
+func scoreSquad(squad []candidate, f formation, constraints squadConstraints) (float64, bool) {
+	cost := 0
+	perSquad := map[int]int{}
+	for _, c := range squad {
+		cost += c.player.Cost
+		perSquad[c.player.SquadId]++
+	}
+	if cost > constraints.budget {
+		return 0, false
+	}
+	for squadId, count := range perSquad {
+		if limit := constraints.squadLimit(squadId); limit >= 0 && count > limit {
+			return 0, false
+		}
+	}
+	value := 0.0
+	for position := 1; position <= len(types.Position); position++ {
+		values := []float64{}
+		for _, c := range squad {
+			if c.position == position {
+				values = insertTop(values, c.value, len(squad))
+			}
+		}
+		if len(values) < f.counts[position] {
+			return 0, false
+		}
+		for i, v := range values {
+			if i < f.counts[position] {
+				value += v
+			} else {
+				value += v * constraints.benchWeight
+			}
+		}
+	}
+	return value, true
+}
+
+// subsets returns every way of choosing k of the candidates
+func subsets(candidates []candidate, k int) [][]candidate {
+	result := [][]candidate{}
+	for _, indexes := range combinations(len(candidates), k) {
+		group := []candidate{}
+		for _, i := range indexes {
+			group = append(group, candidates[i])
+		}
+		result = append(result, group)
+	}
+	return result
+}
+
+func TestSolveSquadMatchesBruteForce(t *testing.T) {
+	small := []formation{
+		{name: "2-2-1", counts: map[int]int{1: 1, 2: 2, 3: 2, 4: 1}},
+		{name: "1-2-2", counts: map[int]int{1: 1, 2: 1, 3: 2, 4: 2}},
+	}
+	pool := map[int]int{1: 4, 2: 6, 3: 6, 4: 5}
+	tests := []struct {
+		name        string
+		seed        int64
+		budget      int
+		maxPerSquad int
+		benchWeight float64
+		starters    bool
+	}{
+		{"starters, loose budget", 1, 100000000, 0, 0, true},
+		{"starters, tight budget", 2, 32000000, 0, 0, true},
+		{"starters, squad limit", 3, 38000000, 2, 0, true},
+		{"squad, no bench weight", 4, 55000000, 4, 0, false},
+		{"squad, bench weight", 5, 58000000, 4, 0.1, false},
+		{"squad, full bench weight", 6, 70000000, 0, 1, false},
+		{"squad, squad limit binds", 7, 60000000, 4, 0.5, false},
+		{"infeasible budget", 8, 20000000, 0, 0.1, false},
+		{"infeasible squad limit", 9, 100000000, 3, 0.1, false},
+	}
+	for _, test := range tests {
+		candidates := testCandidates(rand.New(rand.NewSource(test.seed)), pool)
+		constraints := squadConstraints{
+			budget:       test.budget,
+			quotas:       map[int]int{1: 2, 2: 3, 3: 3, 4: 2},
+			formations:   small,
+			maxPerSquad:  test.maxPerSquad,
+			benchWeight:  test.benchWeight,
+			startersOnly: test.starters,
+		}
+		want, wantFound := bruteForceSquad(candidates, constraints)
+		selection, found := solveSquad(candidates, constraints)
+		if found != wantFound {
+			t.Errorf("%s: found %v, want %v", test.name, found, wantFound)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if math.Abs(selection.value-want) > 1e-9 {
+			t.Errorf("%s: value %.2f, want %.2f", test.name, selection.value, want)
+		}
+
+		// The selection itself has to meet the constraints and be worth what it reports
+		var f formation
+		for _, candidate := range small {
+			if candidate.name == selection.formation {
+				f = candidate
+			}
+		}
+		squad := append(append([]candidate{}, selection.starters...), selection.bench...)
+		value, ok := scoreSquad(squad, f, constraints)
+		if !ok || math.Abs(value-selection.value) > 1e-9 {
+			t.Errorf("%s: selection worth %.2f (valid %v), reported %.2f", test.name, value, ok, selection.value)
+		}
+		cost := 0
+		for _, c := range squad {
+			cost += c.player.Cost
+		}
+		if cost != selection.cost {
+			t.Errorf("%s: selection costs %d, reported %d", test.name, cost, selection.cost)
+		}
+	}
+}
+
+func TestSolveSquadFullSquads(t *testing.T) {
+	// Transfers cap the squads the team already has enough players from at 0, so their players are
+	// worth more but can never be picked
+	midfielder := func(id int, squadId int, value float64) candidate {
+		return candidate{player: types.Player{Id: id, SquadId: squadId, Cost: 5000000, Positions: []int{3}}, position: 3, value: value}
+	}
+	one := []formation{{name: "0-1-0", counts: map[int]int{1: 0, 2: 0, 3: 1, 4: 0}}}
+	tests := []struct {
+		name        string
+		candidates  []candidate
+		squadLimits map[int]int
+		want        int
+	}{
+		{"full squads are passed over", []candidate{midfielder(1, 2, 10), midfielder(2, 3, 10), midfielder(3, 1, 9)}, map[int]int{1: 1, 2: 0, 3: 0}, 3},
+		{"one open squad dominates", []candidate{midfielder(1, 2, 10), midfielder(2, 3, 10), midfielder(3, 1, 9)}, map[int]int{1: 1, 2: 0, 3: 1}, 2},
+		{"a full squad of the best player", []candidate{midfielder(1, 2, 10), midfielder(2, 1, 8), midfielder(3, 1, 9)}, map[int]int{2: 0}, 3},
+	}
+	for _, test := range tests {
+		constraints := squadConstraints{
+			budget:       5000000,
+			formations:   one,
+			squadLimits:  test.squadLimits,
+			startersOnly: true,
+		}
+		want, _ := bruteForceSquad(test.candidates, constraints)
+		selection, found := solveSquad(test.candidates, constraints)
+		if !found {
+			t.Errorf("%s: no selection found", test.name)
+			continue
+		}
+		if len(selection.starters) != 1 || selection.starters[0].player.Id != test.want || selection.value != want {
+			t.Errorf("%s: picked %v worth %.2f, want player %d worth %.2f", test.name, selection.starters, selection.value, test.want, want)
+		}
+	}
+}
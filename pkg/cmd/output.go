@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+var (
+	playerRecordHeader = []string{
+		"id", "position", "name", "team", "cost", "total_points", "games_played", "star_man", "seven_plus",
+		"goals", "assists", "clean_sheets", "cards", "last_3_avg", "last_5_avg",
+		"fixture_gw", "fixture_date", "fixture_competition", "fixture", "fixture_result", "fixture_points",
+	}
+	matchRecordHeader = []string{"gw", "date", "kickoff", "competition", "fixture", "result", "venue", "venue_id", "status"}
+)
+
+// renderPlayer converts a player and the matches of their squad into the rendered form used for output
+func renderPlayer(player types.Player, competitions map[int]types.Competition, squads map[int]types.Squad) types.RenderedPlayer {
+	renderedPlayer := types.RenderedPlayer{
+		Id:           player.Id,
+		Name:         fmt.Sprintf("%s %s", player.FirstName, player.LastName),
+		Team:         player.Team,
+		Position:     player.Job,
+		Cost:         player.CostDisp,
+		CostMillions: float64(player.Cost) / 1000000,
+		TotalPoints:  player.InPlayStats.TotalPoints,
+		GamesPlayed:  player.InPlayStats.GamesPlayed,
+		StarMan:      player.InPlayStats.StarManAwards,
+		SevenPlus:    player.InPlayStats.SevenPlusRatings,
+		Goals:        player.InPlayStats.Goals,
+		Assists:      player.InPlayStats.Assists,
+		CleanSheets:  player.InPlayStats.CleanSheets,
+		Cards:        player.InPlayStats.Cards,
+		Last3Avg:     player.InPlayStats.Last3Avg,
+		Last5Avg:     player.InPlayStats.Last5Avg,
+	}
+	// Generate rendered fixtures
+	renderedFixtures := []types.RenderedMatch{}
+	for _, match := range player.Matches {
+		if match.Status == "complete" {
+			fixture := renderMatch(match, competitions, squads)
+			fixture.Points = player.InPlayStats.MatchScores[fmt.Sprintf("%d", match.Id)]
+			renderedFixtures = append(renderedFixtures, fixture)
+		}
+	}
+	sort.Slice(renderedFixtures, func(i, j int) bool {
+		return renderedFixtures[i].Date < renderedFixtures[j].Date
+	})
+	renderedPlayer.TeamFixtures = renderedFixtures
+	renderedPlayer.Gameweeks = player.SpecialGameweeks
+	return renderedPlayer
+}
+
+// renderMatch converts a match into its rendered form. The feeds only give the id of the ground, so
+// the venue is shown as the home squad
+func renderMatch(match types.Match, competitions map[int]types.Competition, squads map[int]types.Squad) types.RenderedMatch {
+	rendered := types.RenderedMatch{
+		Gw:          match.Gw,
+		Competition: competitions[match.CompetitionId].Name,
+		Fixture:     fmt.Sprintf("%s v %s", squads[match.HomeSquadId].Name, squads[match.AwaySquadId].Name),
+		Date:        strings.Split(match.Date, "T")[0],
+		Venue:       squads[match.HomeSquadId].Name,
+		VenueId:     match.VenueId,
+		Status:      match.Status,
+	}
+	// Dates come with or without a time zone
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if kickoff, err := time.Parse(layout, match.Date); err == nil {
+			rendered.Kickoff = kickoff.Format("15:04")
+			break
+		}
+	}
+	// Scheduled matches have no result yet
+	if match.Status != "scheduled" {
+		rendered.Result = fmt.Sprintf("%d v %d", match.HomeScore, match.AwayScore)
+	}
+	return rendered
+}
+
+// writePlayers outputs the rendered players in a machine readable format, one record per player fixture
+func writePlayers(format string, players []types.RenderedPlayer) error {
+	if format == "json" {
+		return writeJSON(players)
+	}
+	records := [][]string{}
+	for _, player := range players {
+		record := []string{
+			fmt.Sprintf("%d", player.Id),
+			player.Position,
+			player.Name,
+			player.Team,
+			fmt.Sprintf("%.2f", player.CostMillions),
+			fmt.Sprintf("%d", player.TotalPoints),
+			fmt.Sprintf("%d", player.GamesPlayed),
+			fmt.Sprintf("%d", player.StarMan),
+			fmt.Sprintf("%d", player.SevenPlus),
+			fmt.Sprintf("%d", player.Goals),
+			fmt.Sprintf("%d", player.Assists),
+			fmt.Sprintf("%d", player.CleanSheets),
+			fmt.Sprintf("%d", player.Cards),
+			fmt.Sprintf("%.2f", player.Last3Avg),
+			fmt.Sprintf("%.2f", player.Last5Avg),
+		}
+		if len(player.TeamFixtures) == 0 {
+			records = append(records, append(record, "", "", "", "", "", ""))
+			continue
+		}
+		for _, fixture := range player.TeamFixtures {
+			fixtureRecord := append([]string{}, record...)
+			records = append(records, append(fixtureRecord,
+				fmt.Sprintf("%d", fixture.Gw),
+				fixture.Date,
+				fixture.Competition,
+				fixture.Fixture,
+				fixture.Result,
+				fmt.Sprintf("%d", fixture.Points),
+			))
+		}
+	}
+	return writeRecords(format, playerRecordHeader, records)
+}
+
+// writeMatches outputs the rendered matches in a machine readable format
+func writeMatches(format string, matches []types.RenderedMatch) error {
+	if format == "json" {
+		return writeJSON(matches)
+	}
+	records := [][]string{}
+	for _, match := range matches {
+		records = append(records, []string{
+			fmt.Sprintf("%d", match.Gw),
+			match.Date,
+			match.Kickoff,
+			match.Competition,
+			match.Fixture,
+			match.Result,
+			match.Venue,
+			fmt.Sprintf("%d", match.VenueId),
+			match.Status,
+		})
+	}
+	return writeRecords(format, matchRecordHeader, records)
+}
+
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeRecords outputs a header and records as csv, tsv, markdown or a table
+func writeRecords(format string, header []string, records [][]string) error {
+	switch format {
+	case "csv", "tsv":
+		w := csv.NewWriter(os.Stdout)
+		if format == "tsv" {
+			w.Comma = '\t'
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(records); err != nil {
+			return err
+		}
+		return w.Error()
+	case "markdown", "table":
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		headerRow := table.Row{}
+		for _, column := range header {
+			headerRow = append(headerRow, column)
+		}
+		t.AppendHeader(headerRow)
+		for _, record := range records {
+			row := table.Row{}
+			for _, value := range record {
+				row = append(row, value)
+			}
+			t.AppendRow(row)
+		}
+		if format == "markdown" {
+			t.RenderMarkdown()
+		} else {
+			t.Render()
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestRenderMatchKickoff(t *testing.T) {
+	tests := []struct {
+		date    string
+		kickoff string
+	}{
+		{"2022-08-03T15:00:00", "15:00"},
+		{"2022-08-03T19:45:00+01:00", "19:45"},
+		{"2022-08-03T12:30:00Z", "12:30"},
+		{"2022-08-03", ""},
+	}
+	for _, test := range tests {
+		match := testResult(1, 1, 2, 1, 0)
+		match.Date = test.date
+		rendered := renderMatch(match, nil, testSquads)
+		if rendered.Kickoff != test.kickoff || rendered.Date != "2022-08-03" {
+			t.Errorf("%s: kickoff %q on %s, want %q on 2022-08-03", test.date, rendered.Kickoff, rendered.Date, test.kickoff)
+		}
+		if rendered.Venue != "Arsenal" {
+			t.Errorf("%s: venue %q, want the home squad Arsenal", test.date, rendered.Venue)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// priceChangeThreshold is the share of a player's selections that net transfers must reach
+// before a price change is predicted
+const priceChangeThreshold = 0.1
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+type (
+	Prices struct {
+		PlayerNames []string `arg:"" optional:"" help:"names or ids of players whose price history to show, default is the biggest movers"`
+		Window      int      `help:"number of rounds to measure price changes over" default:"3"`
+		Top         int      `help:"number of players to show in each list" default:"10"`
+		Where       string   `help:"filter expression to select the players, as for display --where"`
+		Output      string   `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// pricePoint is a player's price in a round
+	pricePoint struct {
+		Round int `json:"round"`
+		Price int `json:"price"`
+	}
+
+	// priceSummary is a player's price history, recent change and predicted next move
+	priceSummary struct {
+		Id           int          `json:"id"`
+		Name         string       `json:"name"`
+		Team         string       `json:"team"`
+		Cost         int          `json:"cost"`
+		Change       int          `json:"change"`
+		NetTransfers int          `json:"net_transfers"`
+		Selections   int          `json:"selections"`
+		Pressure     float64      `json:"pressure"`
+		Prediction   string       `json:"prediction"`
+		History      []pricePoint `json:"history"`
+	}
+)
+
+func (p *Prices) Run(globals *Globals) error {
+	if p.Window < 1 {
+		return fmt.Errorf("invalid window %d, it must be at least 1 round", p.Window)
+	}
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	teams := newTeamIndex(globals.SquadMap, globals.TeamAliases)
+	expression, err := parseFilters(nil, p.Where, teams)
+	if err != nil {
+		return err
+	}
+
+	if len(p.PlayerNames) > 0 {
+		summaries := []priceSummary{}
+		for _, name := range p.PlayerNames {
+			player, err := resolvePlayer(players, name)
+			if err != nil {
+				return err
+			}
+			summaries = append(summaries, summarisePrices(player, p.Window))
+		}
+		if p.Output == "table" {
+			for _, summary := range summaries {
+				renderPriceHistory(summary)
+			}
+			return nil
+		}
+		return writePriceSummaries(p.Output, summaries)
+	}
+
+	summaries := []priceSummary{}
+	for _, player := range players {
+		if expression != nil && !expression.Match(playerRecord(&player)) {
+			continue
+		}
+		summaries = append(summaries, summarisePrices(player, p.Window))
+	}
+
+	// Biggest risers and fallers over the window
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Change > summaries[j].Change
+	})
+	risers := topSummaries(summaries, p.Top, func(s priceSummary) bool { return s.Change > 0 })
+	fallers := topSummaries(reverseSummaries(summaries), p.Top, func(s priceSummary) bool { return s.Change < 0 })
+
+	// Players most likely to change price next
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Pressure > summaries[j].Pressure
+	})
+	rising := topSummaries(summaries, p.Top, func(s priceSummary) bool { return s.Prediction == "rise" })
+	falling := topSummaries(reverseSummaries(summaries), p.Top, func(s priceSummary) bool { return s.Prediction == "fall" })
+
+	switch p.Output {
+	case "table":
+		window := fmt.Sprintf("last %d rounds", p.Window)
+		renderPriceMovers(fmt.Sprintf("Biggest risers, %s", window), risers)
+		renderPriceMovers(fmt.Sprintf("Biggest fallers, %s", window), fallers)
+		renderPriceMovers("Predicted to rise", rising)
+		renderPriceMovers("Predicted to fall", falling)
+		return nil
+	case "json":
+		return writeJSON(map[string][]priceSummary{
+			"risers":            risers,
+			"fallers":           fallers,
+			"predicted_to_rise": rising,
+			"predicted_to_fall": falling,
+		})
+	}
+	records := [][]string{}
+	for _, list := range []struct {
+		name      string
+		summaries []priceSummary
+	}{{"risers", risers}, {"fallers", fallers}, {"predicted_to_rise", rising}, {"predicted_to_fall", falling}} {
+		for _, record := range priceRecords(list.summaries) {
+			records = append(records, append([]string{list.name}, record...))
+		}
+	}
+	return writeRecords(p.Output, append([]string{"list"}, priceRecordHeader...), records)
+}
+
+var priceRecordHeader = []string{"id", "name", "team", "cost", "change", "net_transfers", "selections", "pressure", "prediction", "history"}
+
+// roundPrices returns the player's prices keyed by round number
+func roundPrices(player types.Player) map[int]int {
+	prices := map[int]int{}
+	for key, price := range player.InPlayStats.Prices {
+		round, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		prices[round] = price
+	}
+	return prices
+}
+
+// summarisePrices works out a player's price change over the last window rounds and predicts the next
+// change from their net transfers as a share of their selections
+func summarisePrices(player types.Player, window int) priceSummary {
+	summary := priceSummary{
+		Id:           player.Id,
+		Name:         playerName(player),
+		Team:         player.Team,
+		Cost:         player.Cost,
+		NetTransfers: player.InPlayStats.MonthlyTransfersIn - player.InPlayStats.MonthlyTransfersOut,
+		Selections:   player.InPlayStats.Selections,
+		Prediction:   "hold",
+		History:      []pricePoint{},
+	}
+	prices := roundPrices(player)
+	for _, round := range lastGameweeks(prices, len(prices)) {
+		summary.History = append(summary.History, pricePoint{Round: round, Price: prices[round]})
+	}
+
+	// The change is measured against the current cost, which may have moved since the last round
+	if len(summary.History) > 0 {
+		start := len(summary.History) - window
+		if start > len(summary.History)-1 {
+			start = len(summary.History) - 1
+		}
+		if start < 0 {
+			start = 0
+		}
+		summary.Change = player.Cost - summary.History[start].Price
+	}
+
+	selections := summary.Selections
+	if selections < 1 {
+		selections = 1
+	}
+	summary.Pressure = float64(summary.NetTransfers) / float64(selections)
+	switch {
+	case summary.Pressure >= priceChangeThreshold:
+		summary.Prediction = "rise"
+	case summary.Pressure <= -priceChangeThreshold:
+		summary.Prediction = "fall"
+	}
+	return summary
+}
+
+// topSummaries returns up to n of the summaries that meet the condition, in their current order
+func topSummaries(summaries []priceSummary, n int, condition func(priceSummary) bool) []priceSummary {
+	top := []priceSummary{}
+	for _, summary := range summaries {
+		if n > 0 && len(top) == n {
+			break
+		}
+		if condition(summary) {
+			top = append(top, summary)
+		}
+	}
+	return top
+}
+
+func reverseSummaries(summaries []priceSummary) []priceSummary {
+	reversed := make([]priceSummary, len(summaries))
+	for i, summary := range summaries {
+		reversed[len(summaries)-1-i] = summary
+	}
+	return reversed
+}
+
+// sparkline draws the prices as a line of block characters scaled between the lowest and highest price
+func sparkline(history []pricePoint) string {
+	if len(history) == 0 {
+		return ""
+	}
+	low, high := history[0].Price, history[0].Price
+	for _, point := range history {
+		if point.Price < low {
+			low = point.Price
+		}
+		if point.Price > high {
+			high = point.Price
+		}
+	}
+	line := []rune{}
+	for _, point := range history {
+		level := len(sparkBlocks) / 2
+		if high > low {
+			level = (point.Price - low) * (len(sparkBlocks) - 1) / (high - low)
+		}
+		line = append(line, sparkBlocks[level])
+	}
+	return string(line)
+}
+
+func priceRecords(summaries []priceSummary) [][]string {
+	records := [][]string{}
+	for _, summary := range summaries {
+		history := []string{}
+		for _, point := range summary.History {
+			history = append(history, fmt.Sprintf("%.2f", float64(point.Price)/1000000))
+		}
+		records = append(records, []string{
+			fmt.Sprintf("%d", summary.Id),
+			summary.Name,
+			summary.Team,
+			fmt.Sprintf("%.2f", float64(summary.Cost)/1000000),
+			fmt.Sprintf("%.2f", float64(summary.Change)/1000000),
+			fmt.Sprintf("%d", summary.NetTransfers),
+			fmt.Sprintf("%d", summary.Selections),
+			fmt.Sprintf("%.3f", summary.Pressure),
+			summary.Prediction,
+			strings.Join(history, " "),
+		})
+	}
+	return records
+}
+
+func writePriceSummaries(format string, summaries []priceSummary) error {
+	if format == "json" {
+		return writeJSON(summaries)
+	}
+	return writeRecords(format, priceRecordHeader, priceRecords(summaries))
+}
+
+// renderPriceMovers shows a list of players with their recent price trajectory
+func renderPriceMovers(title string, summaries []priceSummary) {
+	rows := []table.Row{}
+	for _, summary := range summaries {
+		rows = append(rows, table.Row{
+			summary.Name,
+			summary.Team,
+			formatCost(summary.Cost),
+			formatCostChange(summary.Change),
+			sparkline(summary.History),
+			summary.NetTransfers,
+			summary.Selections,
+			fmt.Sprintf("%.1f%%", summary.Pressure*100),
+		})
+	}
+	renderDiffTable(title, table.Row{"Player", "Team", "Cost", "Change", "Trend", "Net Transfers", "Selections", "Pressure"}, rows)
+}
+
+// renderPriceHistory shows a player's price in each round and their predicted next move
+func renderPriceHistory(summary priceSummary) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(fmt.Sprintf("%s (%s) %s %s", summary.Name, summary.Team, formatCost(summary.Cost), sparkline(summary.History)))
+	t.AppendHeader(table.Row{"Round", "Price", "Change"})
+	previous := 0
+	for i, point := range summary.History {
+		change := ""
+		if i > 0 {
+			change = formatCostChange(point.Price - previous)
+		}
+		t.AppendRow(table.Row{point.Round, formatCost(point.Price), change})
+		previous = point.Price
+	}
+	t.AppendSeparator()
+	t.AppendRow(table.Row{"Prediction", summary.Prediction, fmt.Sprintf("net transfers %d, %.1f%% of selections", summary.NetTransfers, summary.Pressure*100)})
+	t.Render()
+}
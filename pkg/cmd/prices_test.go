@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"reflect"
+	"testing"
+)
+
+func TestSummarisePricesChange(t *testing.T) {
+	history := map[string]int{"1": 5000000, "2": 5100000, "9": 5300000, "10": 5200000, "latest": 9900000}
+	tests := []struct {
+		name   string
+		prices map[string]int
+		window int
+		want   int
+	}{
+		{"no price history", map[string]int{}, 3, 0},
+		{"last round", history, 1, 5500000 - 5200000},
+		{"rounds in numeric order", history, 2, 5500000 - 5300000},
+		{"whole history", history, 4, 5500000 - 5000000},
+		{"window longer than the history", history, 10, 5500000 - 5000000},
+		{"window of nothing", history, 0, 5500000 - 5200000},
+		{"negative window", history, -3, 5500000 - 5200000},
+		{"single round", map[string]int{"4": 6000000}, 3, 5500000 - 6000000},
+	}
+	for _, test := range tests {
+		player := types.Player{Id: 1, Cost: 5500000, InPlayStats: types.Stats{Prices: test.prices}}
+		summary := summarisePrices(player, test.window)
+		if summary.Change != test.want {
+			t.Errorf("%s: change %d, want %d", test.name, summary.Change, test.want)
+		}
+	}
+
+	summary := summarisePrices(types.Player{Cost: 5500000, InPlayStats: types.Stats{Prices: history}}, 3)
+	want := []pricePoint{{1, 5000000}, {2, 5100000}, {9, 5300000}, {10, 5200000}}
+	if !reflect.DeepEqual(summary.History, want) {
+		t.Errorf("history %v, want %v", summary.History, want)
+	}
+}
+
+func TestSummarisePricesPrediction(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         int
+		out        int
+		selections int
+		pressure   float64
+		prediction string
+	}{
+		{"no transfers", 0, 0, 1000, 0, "hold"},
+		{"rise at the threshold", 150, 50, 1000, 0.1, "rise"},
+		{"just below the threshold", 149, 50, 1000, 0.099, "hold"},
+		{"fall at the threshold", 50, 150, 1000, -0.1, "fall"},
+		{"no selections counts as one", 3, 0, 0, 3, "rise"},
+	}
+	for _, test := range tests {
+		player := types.Player{InPlayStats: types.Stats{MonthlyTransfersIn: test.in, MonthlyTransfersOut: test.out, Selections: test.selections}}
+		summary := summarisePrices(player, 3)
+		if summary.Pressure != test.pressure || summary.Prediction != test.prediction {
+			t.Errorf("%s: pressure %g and %s, want %g and %s", test.name, summary.Pressure, summary.Prediction, test.pressure, test.prediction)
+		}
+	}
+}
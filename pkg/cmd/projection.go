@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"sort"
+)
+
+const (
+	// formDecay is the weight of each match score relative to the next most recent one
+	formDecay = 0.8
+	// recentMatches is the number of the squad's latest matches used to work out how often a player plays
+	recentMatches = 6
+	// homeBoost is the share expected points rise at home and fall away
+	homeBoost = 0.05
+	// minOpponentFactor and maxOpponentFactor limit how much the opponent changes expected points
+	minOpponentFactor = 0.5
+	maxOpponentFactor = 1.5
+)
+
+// attackingShare is how much of a player's points are assumed to come from goals and assists, rather
+// than clean sheets, for each position when they have neither
+var attackingShare = map[int]float64{1: 0.1, 2: 0.3, 3: 0.7, 4: 0.9}
+
+// projector estimates each player's points in the upcoming game weeks. A player's expected points in
+// a fixture are their recent points per appearance, weighted towards their latest matches, times how
+// often they have played in their squad's recent matches, scaled by the opponent's goals scored and
+// conceded per match against the league average and by home or away
+type projector struct {
+	gws     []int
+	runs    map[int]squadRun
+	squads  map[int]types.Squad
+	matches map[int]types.Match
+	// squadMatches are each squad's completed matches in date order
+	squadMatches map[int][]types.Match
+	// scoredRate and concededRate are each squad's goals per match, with the league averages
+	scoredRate   map[int]float64
+	concededRate map[int]float64
+	leagueRate   float64
+}
+
+func newProjector(matchweeks []types.MatchWeek, next int, squads map[int]types.Squad) *projector {
+	p := &projector{
+		gws:          upcomingGameweeks(matchweeks, next),
+		squads:       squads,
+		matches:      map[int]types.Match{},
+		squadMatches: map[int][]types.Match{},
+		scoredRate:   map[int]float64{},
+		concededRate: map[int]float64{},
+	}
+	p.runs = squadRunMap(matchweeks, p.gws, squads)
+
+	// Goals per match come from the squad stats where the feed has them, otherwise from the results
+	scored := map[int]int{}
+	conceded := map[int]int{}
+	for _, week := range matchweeks {
+		for _, match := range week.MatchesInWeek {
+			p.matches[match.Id] = match
+			if match.Status != "complete" {
+				continue
+			}
+			p.squadMatches[match.HomeSquadId] = append(p.squadMatches[match.HomeSquadId], match)
+			p.squadMatches[match.AwaySquadId] = append(p.squadMatches[match.AwaySquadId], match)
+			scored[match.HomeSquadId] += match.HomeScore
+			conceded[match.HomeSquadId] += match.AwayScore
+			scored[match.AwaySquadId] += match.AwayScore
+			conceded[match.AwaySquadId] += match.HomeScore
+		}
+	}
+	totalGoals, totalPlayed := 0, 0
+	for id, matches := range p.squadMatches {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Date < matches[j].Date
+		})
+		played := len(matches)
+		goals, against := scored[id], conceded[id]
+		if stats := squads[id].Stats; stats.Goals > 0 || stats.Conceded > 0 {
+			goals, against = stats.Goals, stats.Conceded
+		}
+		p.scoredRate[id] = float64(goals) / float64(played)
+		p.concededRate[id] = float64(against) / float64(played)
+		totalGoals += goals
+		totalPlayed += played
+	}
+	if totalPlayed > 0 {
+		p.leagueRate = float64(totalGoals) / float64(totalPlayed)
+	}
+	return p
+}
+
+// projectFixtures returns a player's expected points in each of their squad's upcoming fixtures
+func (p *projector) projectFixtures(player types.Player) []types.ProjectedFixture {
+	perAppearance, appearanceRate := p.form(player)
+	share := attackingShare[player.Positions[0]]
+	stats := player.InPlayStats
+	attacking := float64(stats.Goals + stats.Assists)
+	if attacking+float64(stats.CleanSheets) > 0 {
+		share = attacking / (attacking + float64(stats.CleanSheets))
+	}
+
+	projections := []types.ProjectedFixture{}
+	for _, fixture := range p.runs[player.SquadId].Fixtures {
+		factor := share*p.attackFactor(fixture.OpponentId) + (1-share)*p.defenceFactor(fixture.OpponentId)
+		factor = math.Max(minOpponentFactor, math.Min(maxOpponentFactor, factor))
+		venue := 1 - homeBoost
+		if fixture.Home {
+			venue = 1 + homeBoost
+		}
+		projections = append(projections, types.ProjectedFixture{
+			Gw:       fixture.Gw,
+			Date:     fixture.Date,
+			Opponent: fixture.Opponent,
+			Home:     fixture.Home,
+			Points:   math.Round(perAppearance*appearanceRate*factor*venue*100) / 100,
+		})
+	}
+	return projections
+}
+
+// form returns a player's points per appearance, weighting recent matches more, and the share of
+// their squad's recent matches they appeared in
+func (p *projector) form(player types.Player) (float64, float64) {
+	type appearance struct {
+		date  string
+		score int
+	}
+	appearances := []appearance{}
+	played := map[int]bool{}
+	for _, id := range sortedScoreKeys(player.InPlayStats.MatchScores) {
+		match, ok := p.matches[id]
+		if !ok || match.Status != "complete" {
+			continue
+		}
+		played[id] = true
+		appearances = append(appearances, appearance{match.Date, player.InPlayStats.MatchScores[fmt.Sprintf("%d", id)]})
+	}
+
+	// Without match scores fall back to the season totals
+	if len(appearances) == 0 {
+		if player.InPlayStats.GamesPlayed == 0 {
+			return 0, 0
+		}
+		rate := 1.0
+		if squadPlayed := len(p.squadMatches[player.SquadId]); squadPlayed > player.InPlayStats.GamesPlayed {
+			rate = float64(player.InPlayStats.GamesPlayed) / float64(squadPlayed)
+		}
+		return float64(player.InPlayStats.TotalPoints) / float64(player.InPlayStats.GamesPlayed), rate
+	}
+
+	sort.SliceStable(appearances, func(i, j int) bool {
+		return appearances[i].date > appearances[j].date
+	})
+	weight, weights, total := 1.0, 0.0, 0.0
+	for _, a := range appearances {
+		total += weight * float64(a.score)
+		weights += weight
+		weight *= formDecay
+	}
+
+	recent := p.squadMatches[player.SquadId]
+	if len(recent) > recentMatches {
+		recent = recent[len(recent)-recentMatches:]
+	}
+	rate := 1.0
+	if len(recent) > 0 {
+		count := 0
+		for _, match := range recent {
+			if played[match.Id] {
+				count++
+			}
+		}
+		rate = float64(count) / float64(len(recent))
+	}
+	return total / weights, rate
+}
+
+// attackFactor compares how many goals an opponent concedes per match with the league average
+func (p *projector) attackFactor(opponentId int) float64 {
+	rate, ok := p.concededRate[opponentId]
+	if !ok || p.leagueRate == 0 {
+		return 1
+	}
+	return rate / p.leagueRate
+}
+
+// defenceFactor compares the league average goals per match with how many an opponent scores
+func (p *projector) defenceFactor(opponentId int) float64 {
+	rate, ok := p.scoredRate[opponentId]
+	if !ok || p.leagueRate == 0 {
+		return 1
+	}
+	if rate == 0 {
+		return maxOpponentFactor
+	}
+	return p.leagueRate / rate
+}
+
+// applyProjections sets the projected points of each player
+func applyProjections(players []types.Player, projections *projector) {
+	for i, player := range players {
+		players[i].Projections = projections.projectFixtures(player)
+		players[i].Projected = 0
+		for _, fixture := range players[i].Projections {
+			players[i].Projected += fixture.Points
+		}
+	}
+}
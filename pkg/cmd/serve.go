@@ -0,0 +1,394 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"guysports/playerstats/pkg/helper"
+	"guysports/playerstats/pkg/types"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout is how long requests in progress are given to finish when the server stops
+const shutdownTimeout = 10 * time.Second
+
+type (
+	Serve struct {
+		Listen  string        `help:"address to listen on" default:":8080"`
+		Refresh time.Duration `help:"how often to load the feeds again, 0 to never reload" default:"15m"`
+		Weeks   int           `help:"number of upcoming game weeks to project points over" default:"3"`
+	}
+
+	// serverData is one load of the feeds, replaced as a whole when they are loaded again
+	serverData struct {
+		players      []types.Player
+		playerMap    map[int]types.Player
+		matchweeks   []types.MatchWeek
+		squads       map[int]types.Squad
+		competitions map[int]types.Competition
+	}
+
+	// apiServer answers requests from the latest load of the feeds
+	apiServer struct {
+		globals *Globals
+		weeks   int
+		mutex   sync.RWMutex
+		data    *serverData
+	}
+
+	// apiError is the body of an error response
+	apiError struct {
+		Error string `json:"error"`
+	}
+
+	// apiMatchWeek is a game week with its matches rendered
+	apiMatchWeek struct {
+		Id      int                   `json:"id"`
+		Status  string                `json:"status"`
+		Matches []types.RenderedMatch `json:"matches"`
+	}
+)
+
+func (s *Serve) Run(globals *Globals) error {
+	server := &apiServer{globals: globals, weeks: s.Weeks}
+	if err := server.load(globals); err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{Addr: s.Listen, Handler: server}
+	failed := make(chan error, 1)
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			failed <- err
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "serving on %s\n", s.Listen)
+
+	var refresh <-chan time.Time
+	if s.Refresh > 0 {
+		ticker := time.NewTicker(s.Refresh)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	// Feeds are reloaded away from this loop so that a slow source does not hold up stopping, and a
+	// reload still running when the next is due is left to finish
+	reloaded := make(chan error, 1)
+	reloading := false
+	for {
+		select {
+		case err := <-failed:
+			return err
+		case <-refresh:
+			if reloading {
+				continue
+			}
+			reloading = true
+			go func() {
+				helper.ResetFeedReports()
+				reloaded <- server.reload()
+			}()
+		case err := <-reloaded:
+			// A failed reload keeps serving the data already loaded
+			reloading = false
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "reloading the feeds failed: %v\n", err)
+			}
+		case <-stop:
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			return httpServer.Shutdown(ctx)
+		}
+	}
+}
+
+// reload reads the squads and competitions again then the feeds that depend on them
+func (a *apiServer) reload() error {
+	globals := *a.globals
+	if err := LoadReferenceData(&globals); err != nil {
+		return err
+	}
+	return a.load(&globals)
+}
+
+// load reads the feeds with the squads and competitions in globals and prepares the players as display
+// does
+func (a *apiServer) load(globals *Globals) error {
+	players, matchweeks, err := loadPlayerData(globals, a.weeks)
+	if err != nil {
+		return err
+	}
+
+	data := &serverData{
+		players:      players,
+		playerMap:    map[int]types.Player{},
+		matchweeks:   matchweeks,
+		squads:       globals.SquadMap,
+		competitions: globals.CompetitionMap,
+	}
+	for _, player := range players {
+		data.playerMap[player.Id] = player
+	}
+	a.mutex.Lock()
+	a.data = data
+	a.mutex.Unlock()
+	return nil
+}
+
+func (a *apiServer) current() *serverData {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.data
+}
+
+// ServeHTTP routes the request by its path
+func (a *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		respond(w, r, http.StatusMethodNotAllowed, apiError{"only GET and HEAD are supported"})
+		return
+	}
+	data := a.current()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "players":
+		a.players(w, r, data)
+	case len(parts) == 2 && parts[0] == "players":
+		if player, ok := lookupPlayerId(w, r, data, parts[1]); ok {
+			rendered := renderPlayer(player, data.competitions, data.squads)
+			rendered.Projected = player.Projections
+			respond(w, r, http.StatusOK, rendered)
+		}
+	case len(parts) == 3 && parts[0] == "players" && parts[2] == "matches":
+		if player, ok := lookupPlayerId(w, r, data, parts[1]); ok {
+			respond(w, r, http.StatusOK, renderPlayer(player, data.competitions, data.squads).TeamFixtures)
+		}
+	case len(parts) == 1 && parts[0] == "squads":
+		squads := []types.Squad{}
+		for _, squad := range data.squads {
+			squads = append(squads, squad)
+		}
+		sort.SliceStable(squads, func(i, j int) bool {
+			return squads[i].ID < squads[j].ID
+		})
+		respond(w, r, http.StatusOK, squads)
+	case len(parts) == 1 && parts[0] == "competitions":
+		competitions := []types.Competition{}
+		for _, competition := range data.competitions {
+			competitions = append(competitions, competition)
+		}
+		sort.SliceStable(competitions, func(i, j int) bool {
+			return competitions[i].ID < competitions[j].ID
+		})
+		respond(w, r, http.StatusOK, competitions)
+	case len(parts) == 1 && parts[0] == "matchweeks":
+		weeks := []apiMatchWeek{}
+		for _, week := range data.matchweeks {
+			weeks = append(weeks, a.renderMatchWeek(week, data))
+		}
+		respond(w, r, http.StatusOK, weeks)
+	case len(parts) == 2 && parts[0] == "matchweeks":
+		gw, err := strconv.Atoi(parts[1])
+		if err != nil {
+			respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("invalid game week %q", parts[1])})
+			return
+		}
+		for _, week := range data.matchweeks {
+			if week.Id == gw {
+				respond(w, r, http.StatusOK, a.renderMatchWeek(week, data))
+				return
+			}
+		}
+		respond(w, r, http.StatusNotFound, apiError{fmt.Sprintf("no game week %d", gw)})
+	case len(parts) == 1 && parts[0] == "table":
+		a.table(w, r, data)
+	default:
+		respond(w, r, http.StatusNotFound, apiError{fmt.Sprintf("unknown path %s", r.URL.Path)})
+	}
+}
+
+// players lists the players with the name, filter, sort, limit and columns query parameters of display,
+// e.g. /players?where=cost<=7.5&sort=-total_points&limit=10&columns=name,team,total_points. As with
+// display, players picked by name are shown whatever the filter.
+func (a *apiServer) players(w http.ResponseWriter, r *http.Request, data *serverData) {
+	query := r.URL.Query()
+	teams := newTeamIndex(data.squads, a.globals.TeamAliases)
+	expression, err := parseFilters(query["filter"], query.Get("where"), teams)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	sortKeys, err := parseSortKeys(splitQuery(query["sort"]))
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	columns, err := parseColumns(splitQuery(query["columns"]))
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	if columns[0] != "id" {
+		columns = append([]string{"id"}, columns...)
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("invalid limit %q", value)})
+			return
+		}
+	}
+
+	selected := []types.Player{}
+	for _, player := range data.players {
+		if expression != nil && !expression.Match(playerRecord(&player)) {
+			continue
+		}
+		selected = append(selected, player)
+	}
+	if names := splitQuery(query["name"]); len(names) > 0 && names[0] != "all" {
+		selected = []types.Player{}
+		for _, name := range names {
+			player, err := resolvePlayer(data.players, name)
+			if err != nil {
+				status := http.StatusBadRequest
+				if len(lookupPlayers(data.players, name)) == 0 {
+					status = http.StatusNotFound
+				}
+				respond(w, r, status, apiError{err.Error()})
+				return
+			}
+			selected = append(selected, player)
+		}
+	}
+	sortPlayers(selected, sortKeys)
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	rows := []map[string]interface{}{}
+	for i := range selected {
+		row := map[string]interface{}{}
+		for _, column := range columns {
+			row[column] = playerFields[column].value(&selected[i])
+		}
+		rows = append(rows, row)
+	}
+	respond(w, r, http.StatusOK, rows)
+}
+
+// table shows the standings of each competition with the competition, venue, last and gw query
+// parameters of the table command
+func (a *apiServer) table(w http.ResponseWriter, r *http.Request, data *serverData) {
+	query := r.URL.Query()
+	competitionId := 0
+	if name := query.Get("competition"); name != "" {
+		id, ok := lookupCompetition(data.competitions, name)
+		if !ok {
+			respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("unknown competition %q", name)})
+			return
+		}
+		competitionId = id
+	}
+	venue := query.Get("venue")
+	switch venue {
+	case "":
+		venue = "all"
+	case "all", "home", "away":
+	default:
+		respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("invalid venue %q, use all, home or away", venue)})
+		return
+	}
+	numbers := map[string]int{}
+	for _, name := range []string{"last", "gw"} {
+		if value := query.Get(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("invalid %s %q", name, value)})
+				return
+			}
+			numbers[name] = number
+		}
+	}
+
+	tables := map[string][]standing{}
+	for id, standings := range leagueStandings(data.matchweeks, competitionId, venue, numbers["last"], numbers["gw"], data.squads) {
+		tables[data.competitions[id].Name] = standings
+	}
+	respond(w, r, http.StatusOK, tables)
+}
+
+func (a *apiServer) renderMatchWeek(week types.MatchWeek, data *serverData) apiMatchWeek {
+	rendered := apiMatchWeek{Id: week.Id, Status: week.Status, Matches: []types.RenderedMatch{}}
+	for _, match := range week.MatchesInWeek {
+		renderedMatch := renderMatch(match, data.competitions, data.squads)
+		renderedMatch.Events = renderEvents(match, data.playerMap)
+		rendered.Matches = append(rendered.Matches, renderedMatch)
+	}
+	return rendered
+}
+
+// lookupPlayerId finds a player by the id in the path, responding with an error if there is none
+func lookupPlayerId(w http.ResponseWriter, r *http.Request, data *serverData, value string) (types.Player, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		respond(w, r, http.StatusBadRequest, apiError{fmt.Sprintf("invalid player id %q", value)})
+		return types.Player{}, false
+	}
+	player, ok := data.playerMap[id]
+	if !ok {
+		respond(w, r, http.StatusNotFound, apiError{fmt.Sprintf("no player with id %d", id)})
+		return types.Player{}, false
+	}
+	return player, true
+}
+
+// splitQuery allows repeated query parameters to also hold comma separated values
+func splitQuery(values []string) []string {
+	split := []string{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+	return split
+}
+
+// respond writes the value as JSON with an ETag of its content, answering a request that already
+// has that content with 304 Not Modified
+func respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	w.Header().Set("Content-Type", "application/json")
+	if status == http.StatusOK {
+		etag := fmt.Sprintf("\"%x\"", sha1.Sum(body))
+		w.Header().Set("ETag", etag)
+		for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+			if strings.TrimSpace(match) == etag || strings.TrimSpace(match) == "*" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/filter"
+	"guysports/playerstats/pkg/types"
+	"sort"
+	"strings"
+)
+
+// sortKey orders players by a single field, with a "~" or "-" prefix or ":desc" suffix for descending
+// and a "+" prefix or ":asc" suffix for ascending, the default. The command line takes a leading "-"
+// as a flag, so "~" is the prefix to use there
+type sortKey struct {
+	name       string
+	descending bool
+	value      func(player *types.Player) interface{}
+}
+
+// parseSortKeys resolves the --sort keys against the player fields, keeping the original
+// "position" ordering of goalkeeper, defender, midfielder, forward then team
+func parseSortKeys(keys []string) ([]sortKey, error) {
+	fieldNames := map[string]string{}
+	for name := range playerFields {
+		fieldNames[filter.Normalize(name)] = name
+	}
+
+	sortKeys := []sortKey{}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		descending := strings.HasPrefix(key, "-") || strings.HasPrefix(key, "~")
+		key = strings.TrimLeft(key, "+-~")
+		if parts := strings.SplitN(key, ":", 2); len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "desc":
+				descending = true
+			case "asc":
+				descending = false
+			default:
+				return nil, fmt.Errorf("invalid sort direction %q, use asc or desc", parts[1])
+			}
+			key = parts[0]
+		}
+		if filter.Normalize(key) == "position" {
+			sortKeys = append(sortKeys, sortKey{name: "position", descending: descending, value: positionOrder})
+			if len(keys) == 1 {
+				sortKeys = append(sortKeys, sortKey{name: "team", value: playerFields["team"].value})
+			}
+			continue
+		}
+		name, ok := fieldNames[filter.Normalize(key)]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort key %q\navailable keys: %s", key, strings.Join(playerFieldNames(), ", "))
+		}
+		sortKeys = append(sortKeys, sortKey{name: name, descending: descending, value: playerFields[name].value})
+	}
+	return sortKeys, nil
+}
+
+func positionOrder(player *types.Player) interface{} {
+	if len(player.Positions) == 0 {
+		return 0.0
+	}
+	return float64(player.Positions[0])
+}
+
+// sortPlayers orders the players by each key in turn, leaving them unchanged if no keys are given
+func sortPlayers(players []types.Player, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		for _, key := range keys {
+			order := compareValues(key.value(&players[i]), key.value(&players[j]))
+			if order == 0 {
+				continue
+			}
+			if key.descending {
+				return order > 0
+			}
+			return order < 0
+		}
+		return false
+	})
+}
+
+func compareValues(a interface{}, b interface{}) int {
+	switch left := a.(type) {
+	case float64:
+		right, _ := b.(float64)
+		switch {
+		case left < right:
+			return -1
+		case left > right:
+			return 1
+		}
+	case string:
+		right, _ := b.(string)
+		return strings.Compare(strings.ToLower(left), strings.ToLower(right))
+	}
+	return 0
+}
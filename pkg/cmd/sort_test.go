@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestSortFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"display", "--sort", "~total_points"}, []string{"total_points:desc"}},
+		{[]string{"display", "--sort=~total_points,cost"}, []string{"total_points:desc", "cost:asc"}},
+		{[]string{"display", "--sort", "last_5_avg:desc,+cost"}, []string{"last_5_avg:desc", "cost:asc"}},
+		{[]string{"display", "--sort", "Last5Avg:asc,~position"}, []string{"last_5_avg:asc", "position:desc"}},
+	}
+	for _, test := range tests {
+		var cli struct {
+			Display Display `cmd:""`
+		}
+		parser, err := kong.New(&cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parser.Parse(test.args); err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+		keys, err := parseSortKeys(cli.Display.Sort)
+		if err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+		got := []string{}
+		for _, key := range keys {
+			direction := ":asc"
+			if key.descending {
+				direction = ":desc"
+			}
+			got = append(got, key.name+direction)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: sorted by %v, want %v", test.args, got, test.want)
+		}
+	}
+}
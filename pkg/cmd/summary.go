@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/filter"
+	"guysports/playerstats/pkg/types"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+var defaultSummaryColumns = []string{
+	"position", "name", "team", "cost", "total_points", "games_played", "avg_points",
+	"last_3_avg", "last_5_avg", "goals", "assists", "clean_sheets",
+}
+
+// parseColumns resolves the requested summary columns against the player fields
+func parseColumns(columns []string) ([]string, error) {
+	if len(columns) == 0 {
+		return defaultSummaryColumns, nil
+	}
+	fieldNames := map[string]string{}
+	for name := range playerFields {
+		fieldNames[filter.Normalize(name)] = name
+	}
+	resolved := []string{}
+	for _, column := range columns {
+		name, ok := fieldNames[filter.Normalize(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q\navailable columns: %s", column, strings.Join(playerFieldNames(), ", "))
+		}
+		resolved = append(resolved, name)
+	}
+	return resolved, nil
+}
+
+// formatFieldValue renders a player field value for display, showing costs in millions and whole numbers without decimals
+func formatFieldValue(name string, value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if name == "cost" {
+			return fmt.Sprintf("£%.2fm", v)
+		}
+		if v == math.Trunc(v) {
+			return fmt.Sprintf("%.0f", v)
+		}
+		return fmt.Sprintf("%.2f", v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// displayPlayerSummary shows one row per player with the chosen columns
+func displayPlayerSummary(players []types.Player, columns []string, format string, pageSize int) error {
+	if format == "json" {
+		rows := []map[string]interface{}{}
+		for i := range players {
+			row := map[string]interface{}{}
+			for _, column := range columns {
+				row[column] = playerFields[column].value(&players[i])
+			}
+			rows = append(rows, row)
+		}
+		return writeJSON(rows)
+	}
+
+	records := [][]string{}
+	for i := range players {
+		record := []string{}
+		for _, column := range columns {
+			record = append(record, formatFieldValue(column, playerFields[column].value(&players[i])))
+		}
+		records = append(records, record)
+	}
+	if format != "table" {
+		return writeRecords(format, columns, records)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{}
+	for _, column := range columns {
+		header = append(header, strings.ReplaceAll(column, "_", " "))
+	}
+	t.AppendHeader(header)
+	for _, record := range records {
+		row := table.Row{}
+		for _, value := range record {
+			row = append(row, value)
+		}
+		t.AppendRow(row)
+	}
+	t.SetPageSize(pageSize)
+	t.Render()
+	return nil
+}
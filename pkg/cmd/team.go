@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// teamSize is the number of players in a team
+	teamSize = 15
+	// teamMaxPerSquad is the most players a team may have from one squad
+	teamMaxPerSquad = 3
+)
+
+// teamQuotas are the number of players a team has in each position
+var teamQuotas = map[int]int{1: 2, 2: 5, 3: 5, 4: 3}
+
+type (
+	MyTeam struct {
+		File   string `help:"YAML or JSON file listing the team's players by name or id and the bank in millions, defaults to $PLAYERSTATS_TEAM_FILE"`
+		Next   int    `help:"number of upcoming game weeks to show fixtures and projected points for" default:"5"`
+		Output string `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// myTeam is a team file with its players resolved
+	myTeam struct {
+		players       []types.Player
+		bank          int
+		freeTransfers int
+	}
+
+	// teamPlayer is a player in the team in the form used for output
+	teamPlayer struct {
+		Id          int            `json:"id"`
+		Name        string         `json:"name"`
+		Position    string         `json:"position"`
+		Team        string         `json:"team"`
+		Cost        float64        `json:"cost"`
+		TotalPoints int            `json:"total_points"`
+		Last5Avg    float32        `json:"last_5_avg"`
+		Projected   float64        `json:"projected"`
+		Fixtures    []squadFixture `json:"fixtures"`
+	}
+)
+
+func (m *MyTeam) Run(globals *Globals) error {
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+	applyProjections(players, newProjector(matchweeks, m.Next, globals.SquadMap))
+	team, err := loadTeam(globals, m.File, players, teamMaxPerSquad)
+	if err != nil {
+		return err
+	}
+
+	gws := upcomingGameweeks(matchweeks, m.Next)
+	runs := squadRunMap(matchweeks, gws, globals.SquadMap)
+	teamPlayers := []teamPlayer{}
+	value := 0
+	for _, player := range team.players {
+		value += player.Cost
+		teamPlayers = append(teamPlayers, teamPlayer{
+			Id:          player.Id,
+			Name:        playerName(player),
+			Position:    player.Job,
+			Team:        player.Team,
+			Cost:        float64(player.Cost) / 1000000,
+			TotalPoints: player.InPlayStats.TotalPoints,
+			Last5Avg:    player.InPlayStats.Last5Avg,
+			Projected:   player.Projected,
+			Fixtures:    runs[player.SquadId].Fixtures,
+		})
+	}
+
+	switch m.Output {
+	case "table":
+		renderTeam(teamPlayers, gws, globals.SquadMap, value, team.bank)
+		return nil
+	case "json":
+		return writeJSON(map[string]interface{}{
+			"players":        teamPlayers,
+			"value":          float64(value) / 1000000,
+			"bank":           float64(team.bank) / 1000000,
+			"free_transfers": team.freeTransfers,
+		})
+	}
+	records := [][]string{}
+	for _, player := range teamPlayers {
+		fixtures := []string{}
+		for _, fixture := range player.Fixtures {
+			fixtures = append(fixtures, describeSquadFixture(fixture, globals.SquadMap))
+		}
+		records = append(records, []string{
+			fmt.Sprintf("%d", player.Id),
+			player.Name,
+			player.Position,
+			player.Team,
+			fmt.Sprintf("%.2f", player.Cost),
+			fmt.Sprintf("%d", player.TotalPoints),
+			fmt.Sprintf("%.2f", player.Last5Avg),
+			fmt.Sprintf("%.2f", player.Projected),
+			strings.Join(fixtures, " "),
+		})
+	}
+	return writeRecords(m.Output, []string{"id", "name", "position", "team", "cost", "total_points", "last_5_avg", "projected", "fixtures"}, records)
+}
+
+// loadTeam reads the team file, taken from the flag or the environment, resolves its players and checks
+// the team has 15 players, two goalkeepers, five defenders, five midfielders and three forwards, and no
+// more than maxPerSquad players from one squad when that is above 0
+func loadTeam(globals *Globals, file string, players []types.Player, maxPerSquad int) (myTeam, error) {
+	if file == "" {
+		file = globals.TeamFile
+	}
+	if file == "" {
+		return myTeam{}, fmt.Errorf("no team file given, use --file or set PLAYERSTATS_TEAM_FILE")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return myTeam{}, err
+	}
+	// YAML is a superset of JSON so both are read the same way
+	teamFile := types.TeamFile{}
+	if err := yaml.Unmarshal(data, &teamFile); err != nil {
+		return myTeam{}, fmt.Errorf("%s: %v", file, err)
+	}
+
+	team := myTeam{
+		bank:          int(teamFile.Bank*1000000 + 0.5),
+		freeTransfers: 1,
+	}
+	if teamFile.FreeTransfers != nil {
+		team.freeTransfers = *teamFile.FreeTransfers
+	}
+	seen := map[int]bool{}
+	for _, name := range teamFile.Players {
+		player, err := resolvePlayer(players, name)
+		if err != nil {
+			return myTeam{}, fmt.Errorf("%s: %v", file, err)
+		}
+		if seen[player.Id] {
+			return myTeam{}, fmt.Errorf("%s: %s is in the team more than once", file, playerName(player))
+		}
+		seen[player.Id] = true
+		team.players = append(team.players, player)
+	}
+	if len(team.players) == 0 {
+		return myTeam{}, fmt.Errorf("%s: the team has no players", file)
+	}
+	if problems := validateTeam(team.players, maxPerSquad, globals.SquadMap); len(problems) > 0 {
+		return myTeam{}, fmt.Errorf("%s: the team is not valid\n  %s", file, strings.Join(problems, "\n  "))
+	}
+	sort.SliceStable(team.players, func(i, j int) bool {
+		a, b := team.players[i], team.players[j]
+		if a.Positions[0] != b.Positions[0] {
+			return a.Positions[0] < b.Positions[0]
+		}
+		return a.Cost > b.Cost
+	})
+	return team, nil
+}
+
+// validateTeam returns what is wrong with a team's size, positions and players from each squad, naming
+// the players involved
+func validateTeam(players []types.Player, maxPerSquad int, squads map[int]types.Squad) []string {
+	problems := []string{}
+	if len(players) != teamSize {
+		problems = append(problems, fmt.Sprintf("it has %d players instead of %d", len(players), teamSize))
+	}
+	positions := map[int][]string{}
+	bySquad := map[int][]string{}
+	for _, player := range players {
+		positions[player.Positions[0]] = append(positions[player.Positions[0]], playerName(player))
+		bySquad[player.SquadId] = append(bySquad[player.SquadId], playerName(player))
+	}
+	for position := 1; position <= len(types.Position); position++ {
+		if names := positions[position]; len(names) != teamQuotas[position] {
+			problem := fmt.Sprintf("it has %d %ss instead of %d", len(names), types.Position[position], teamQuotas[position])
+			if len(names) > 0 {
+				problem += ": " + strings.Join(names, ", ")
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if maxPerSquad > 0 {
+		squadIds := []int{}
+		for squadId := range bySquad {
+			squadIds = append(squadIds, squadId)
+		}
+		sort.Ints(squadIds)
+		for _, squadId := range squadIds {
+			if names := bySquad[squadId]; len(names) > maxPerSquad {
+				problems = append(problems, fmt.Sprintf("it has %d players from %s, more than %d: %s", len(names), squads[squadId].Name,
+					maxPerSquad, strings.Join(names, ", ")))
+			}
+		}
+	}
+	return problems
+}
+
+// describeSquadFixture shows an upcoming fixture by the opponent's abbreviation, in upper case for
+// home matches and lower case for away matches, e.g. "ARS (H)" or "ars (A)"
+func describeSquadFixture(fixture squadFixture, squads map[int]types.Squad) string {
+	abbreviation := squadAbbreviations(squads)[fixture.OpponentId]
+	if fixture.Home {
+		return abbreviation + " (H)"
+	}
+	return strings.ToLower(abbreviation) + " (A)"
+}
+
+func renderTeam(players []teamPlayer, gws []int, squads map[int]types.Squad, value int, bank int) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(fmt.Sprintf("Team value %s, bank %s, total %s", formatCost(value), formatCost(bank), formatCost(value+bank)))
+	header := table.Row{"Position", "Player", "Team", "Cost", "Points", "Last 5 Avg", "Projected"}
+	for _, gw := range gws {
+		header = append(header, fmt.Sprintf("GW%d", gw))
+	}
+	t.AppendHeader(header)
+	projected := 0.0
+	for _, player := range players {
+		row := table.Row{player.Position, player.Name, player.Team, fmt.Sprintf("£%.2fm", player.Cost), player.TotalPoints, fmt.Sprintf("%.2f", player.Last5Avg), fmt.Sprintf("%.2f", player.Projected)}
+		for _, gw := range gws {
+			cells := []string{}
+			for _, fixture := range player.Fixtures {
+				if fixture.Gw == gw {
+					cells = append(cells, difficultyColour(fixture.Difficulty).Sprintf(" %s ", describeSquadFixture(fixture, squads)))
+				}
+			}
+			if len(cells) == 0 {
+				cells = append(cells, "-")
+			}
+			row = append(row, strings.Join(cells, "\n"))
+		}
+		t.AppendRow(row)
+		projected += player.Projected
+	}
+	t.AppendSeparator()
+	t.AppendRow(table.Row{"", "Total", "", formatCost(value), "", "", fmt.Sprintf("%.2f", projected)})
+	t.Render()
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"guysports/playerstats/pkg/types"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultTeamAliases are the common nicknames accepted for teams, in addition to any set in TEAM_ALIASES
+var defaultTeamAliases = map[string]string{
+	"Spurs":      "Tottenham Hotspur",
+	"Man Utd":    "Manchester United",
+	"Man United": "Manchester United",
+	"Man City":   "Manchester City",
+	"Villa":      "Aston Villa",
+	"Palace":     "Crystal Palace",
+	"Forest":     "Nottingham Forest",
+	"Toon":       "Newcastle United",
+	"Hammers":    "West Ham United",
+	"Saints":     "Southampton",
+	"Gunners":    "Arsenal",
+	"Blues":      "Chelsea",
+	"Reds":       "Liverpool",
+	"Seagulls":   "Brighton & Hove Albion",
+	"Bees":       "Brentford",
+	"Cherries":   "AFC Bournemouth",
+}
+
+// teamIndex resolves the full names, short names, abbreviations and aliases of the squads to squad ids
+type teamIndex struct {
+	squads map[int]types.Squad
+	names  map[string]int
+}
+
+// newTeamIndex builds the team index from the loaded squads and the configured aliases,
+// given as a comma separated list of alias=team pairs where team is any recognised name or id.
+// A name that more than one squad has is left out rather than resolving to either of them.
+func newTeamIndex(squads map[int]types.Squad, aliases string) *teamIndex {
+	index := teamIndex{
+		squads: squads,
+		names:  map[string]int{},
+	}
+	abbreviations := squadAbbreviations(squads)
+	ambiguous := map[string]bool{}
+	for id, squad := range squads {
+		for _, name := range []string{squad.Name, squad.ShortName, abbreviations[id], strconv.Itoa(id)} {
+			key := foldName(name)
+			if other, ok := index.names[key]; ok && other != id {
+				ambiguous[key] = true
+			}
+			index.names[key] = id
+		}
+	}
+	for key := range ambiguous {
+		delete(index.names, key)
+	}
+	delete(index.names, "")
+
+	for alias, team := range defaultTeamAliases {
+		if id, ok := index.names[foldName(team)]; ok {
+			index.names[foldName(alias)] = id
+		}
+	}
+	for _, pair := range strings.Split(aliases, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if id, ok := index.names[foldName(parts[1])]; ok {
+			index.names[foldName(parts[0])] = id
+		}
+	}
+	return &index
+}
+
+// lookup returns the squad id for a team name, short name, abbreviation or alias
+func (t *teamIndex) lookup(name string) (int, bool) {
+	id, ok := t.names[foldName(name)]
+	return id, ok
+}
+
+// canonicalName returns the full name of the team a name refers to, or the name itself if it is not a known team
+func (t *teamIndex) canonicalName(name string) string {
+	if id, ok := t.lookup(name); ok {
+		return t.squads[id].Name
+	}
+	return name
+}
+
+// squadAbbreviations returns the three letter code of each squad. Codes derived from the names are
+// kept apart from each other and from the codes in the feed by trying later letters of the name, so
+// that if another squad has MCI in the feed, Manchester City becomes MAN.
+func squadAbbreviations(squads map[int]types.Squad) map[int]string {
+	ids := []int{}
+	for id := range squads {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	abbreviations := map[int]string{}
+	taken := map[string]bool{}
+	for _, id := range ids {
+		if abbreviation := squads[id].Abbreviation; abbreviation != "" {
+			abbreviations[id] = abbreviation
+			taken[abbreviation] = true
+		}
+	}
+	derived := map[string][]int{}
+	for _, id := range ids {
+		if squads[id].Abbreviation == "" {
+			abbreviation := squadAbbreviation(squads[id])
+			derived[abbreviation] = append(derived[abbreviation], id)
+		}
+	}
+	// Squads that alone derive a code keep it, the rest take the next code free in id order
+	for abbreviation, sharing := range derived {
+		if len(sharing) == 1 && !taken[abbreviation] {
+			abbreviations[sharing[0]] = abbreviation
+			taken[abbreviation] = true
+		}
+	}
+	for _, id := range ids {
+		if _, ok := abbreviations[id]; ok {
+			continue
+		}
+		abbreviation := squadAbbreviation(squads[id])
+		for _, candidate := range abbreviationCandidates(squads[id].Name) {
+			if !taken[candidate] {
+				abbreviation = candidate
+				break
+			}
+		}
+		abbreviations[id] = abbreviation
+		taken[abbreviation] = true
+	}
+	return abbreviations
+}
+
+// abbreviationCandidates returns the codes made of the first letter of a name and any two later
+// letters in order, starting with the code squadAbbreviation derives
+func abbreviationCandidates(name string) []string {
+	letters := []rune{}
+	for _, r := range strings.ToUpper(name) {
+		if unicode.IsLetter(r) {
+			letters = append(letters, r)
+		}
+	}
+	candidates := []string{squadAbbreviation(types.Squad{Name: name})}
+	for i := 1; i < len(letters); i++ {
+		for j := i + 1; j < len(letters); j++ {
+			candidates = append(candidates, string([]rune{letters[0], letters[i], letters[j]}))
+		}
+	}
+	return candidates
+}
+
+// squadAbbreviation returns the three letter code of a squad, deriving one from the name if the feed has none,
+// e.g. Arsenal is ARS and Manchester United is MUN. Use squadAbbreviations for codes that are unique.
+func squadAbbreviation(squad types.Squad) string {
+	if squad.Abbreviation != "" {
+		return squad.Abbreviation
+	}
+	words := strings.FieldsFunc(squad.Name, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	letters := []rune{}
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		letters = []rune(words[0])
+	default:
+		letters = append([]rune(words[0])[:1], []rune(words[len(words)-1])...)
+	}
+	if len(letters) > 3 {
+		letters = letters[:3]
+	}
+	return strings.ToUpper(string(letters))
+}
+
+// squadShortName returns the short name of a squad, falling back to the full name
+func squadShortName(squad types.Squad) string {
+	if squad.ShortName != "" {
+		return squad.ShortName
+	}
+	return squad.Name
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"guysports/playerstats/pkg/filter"
+	"guysports/playerstats/pkg/types"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type (
+	Transfers struct {
+		File          string `help:"YAML or JSON file listing the team's players by name or id and the bank in millions, defaults to $PLAYERSTATS_TEAM_FILE"`
+		Max           int    `help:"largest number of transfers to make in one move" default:"2"`
+		Next          int    `help:"number of upcoming game weeks to project points over for the projected objective" default:"3"`
+		Objective     string `help:"numeric player field the moves gain the most of, as for optimize --objective" default:"projected"`
+		FreeTransfers int    `help:"free transfers available, -1 to take them from the team file" default:"-1"`
+		HitCost       int    `help:"points deducted for each transfer beyond the free transfers" default:"4"`
+		MaxPerTeam    int    `help:"maximum number of players from one team, 0 for no limit" default:"3"`
+		Top           int    `help:"number of moves to show" default:"10"`
+		Where         string `help:"filter expression to select the players that can be brought in, as for display --where"`
+		Output        string `help:"output format, one of table, json, csv, tsv or markdown" enum:"table,json,csv,tsv,markdown" default:"table"`
+	}
+
+	// transferMove is a set of players sold and bought together
+	transferMove struct {
+		Out  []string `json:"out"`
+		In   []string `json:"in"`
+		Gain float64  `json:"projected_gain"`
+		Hits int      `json:"hit_cost"`
+		Net  float64  `json:"net_gain"`
+		Bank float64  `json:"bank_after"`
+	}
+)
+
+func (t *Transfers) Run(globals *Globals) error {
+	players, err := loadPlayers(globals)
+	if err != nil {
+		return err
+	}
+	matchweeks, err := loadMatchWeeks(globals)
+	if err != nil {
+		return err
+	}
+	applyProjections(players, newProjector(matchweeks, t.Next, globals.SquadMap))
+	attachGameweeks(players, newGameweekCalendar(matchweeks), upcomingGameweeks(matchweeks, 0), globals.CompetitionMap)
+	objective, err := playerObjective(t.Objective)
+	if err != nil {
+		return err
+	}
+	team, err := loadTeam(globals, t.File, players, t.MaxPerTeam)
+	if err != nil {
+		return err
+	}
+	if t.FreeTransfers >= 0 {
+		team.freeTransfers = t.FreeTransfers
+	}
+	teams := newTeamIndex(globals.SquadMap, globals.TeamAliases)
+	expression, err := parseFilters(nil, t.Where, teams)
+	if err != nil {
+		return err
+	}
+
+	// Players that can be brought in, valued by the objective
+	inTeam := map[int]bool{}
+	teamCounts := map[int]int{}
+	for _, player := range team.players {
+		inTeam[player.Id] = true
+		teamCounts[player.SquadId]++
+	}
+	candidates := []candidate{}
+	for _, player := range players {
+		if inTeam[player.Id] || (expression != nil && !expression.Match(playerRecord(&player))) {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			player:   player,
+			position: player.Positions[0],
+			value:    objective(&player),
+		})
+	}
+
+	moves := []transferMove{}
+	for n := 1; n <= t.Max && n <= len(team.players); n++ {
+		for _, sold := range combinations(len(team.players), n) {
+			move, ok := bestReplacements(team, sold, candidates, objective, teamCounts, t.MaxPerTeam, globals.SquadMap)
+			if !ok || move.Gain <= 0 {
+				continue
+			}
+			if n > team.freeTransfers {
+				move.Hits = (n - team.freeTransfers) * t.HitCost
+			}
+			move.Net = move.Gain - float64(move.Hits)
+			moves = append(moves, move)
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Net > moves[j].Net
+	})
+	if t.Top > 0 && len(moves) > t.Top {
+		moves = moves[:t.Top]
+	}
+
+	switch t.Output {
+	case "table":
+		rows := []table.Row{}
+		for i, move := range moves {
+			rows = append(rows, table.Row{i + 1, strings.Join(move.Out, "\n"), strings.Join(move.In, "\n"),
+				fmt.Sprintf("%.2f", move.Gain), move.Hits, fmt.Sprintf("%.2f", move.Net), fmt.Sprintf("£%.2fm", move.Bank)})
+		}
+		title := fmt.Sprintf("Best transfers by %s, %d free", t.Objective, team.freeTransfers)
+		if filter.Normalize(t.Objective) == "projected" {
+			title = fmt.Sprintf("Best transfers by %s over the next %d game weeks, %d free", t.Objective, t.Next, team.freeTransfers)
+		}
+		renderDiffTable(title, table.Row{"Rank", "Out", "In", "Gain", "Hit", "Net", "Bank"}, rows)
+		return nil
+	case "json":
+		return writeJSON(moves)
+	}
+	records := [][]string{}
+	for i, move := range moves {
+		records = append(records, []string{
+			fmt.Sprintf("%d", i+1),
+			strings.Join(move.Out, "; "),
+			strings.Join(move.In, "; "),
+			fmt.Sprintf("%.2f", move.Gain),
+			fmt.Sprintf("%d", move.Hits),
+			fmt.Sprintf("%.2f", move.Net),
+			fmt.Sprintf("%.2f", move.Bank),
+		})
+	}
+	return writeRecords(t.Output, []string{"rank", "out", "in", "projected_gain", "hit_cost", "net_gain", "bank_after"}, records)
+}
+
+// bestReplacements finds the players that gain the most of the objective in place of the sold players,
+// keeping the positions of the team, the club limit and the money available from the bank and sales
+func bestReplacements(team myTeam, sold []int, candidates []candidate, objective func(player *types.Player) float64, teamCounts map[int]int, maxPerTeam int, squads map[int]types.Squad) (transferMove, bool) {
+	move := transferMove{Out: []string{}, In: []string{}}
+	budget := team.bank
+	positions := map[int]int{}
+	soldCounts := map[int]int{}
+	soldValue := 0.0
+	for _, index := range sold {
+		player := team.players[index]
+		budget += player.Cost
+		positions[player.Positions[0]]++
+		soldCounts[player.SquadId]++
+		soldValue += objective(&player)
+		move.Out = append(move.Out, playerName(player))
+	}
+
+	limits := map[int]int{}
+	if maxPerTeam > 0 {
+		for squadId := range squads {
+			limits[squadId] = maxPerTeam - teamCounts[squadId] + soldCounts[squadId]
+			if limits[squadId] < 0 {
+				limits[squadId] = 0
+			}
+		}
+	}
+	selection, ok := solveSquad(candidates, squadConstraints{
+		budget:       budget,
+		formations:   []formation{{counts: positions}},
+		squadLimits:  limits,
+		startersOnly: true,
+	})
+	if !ok {
+		return move, false
+	}
+	for _, c := range selection.starters {
+		move.In = append(move.In, playerName(c.player))
+	}
+	move.Gain = selection.value - soldValue
+	move.Bank = float64(budget-selection.cost) / 1000000
+	return move, true
+}
+
+// combinations returns every way of choosing k of the indexes 0 to n-1, in increasing order
+func combinations(n int, k int) [][]int {
+	result := [][]int{}
+	combination := make([]int, k)
+	var choose func(start int, depth int)
+	choose = func(start int, depth int) {
+		if depth == k {
+			result = append(result, append([]int{}, combination...))
+			return
+		}
+		for i := start; i <= n-(k-depth); i++ {
+			combination[depth] = i
+			choose(i+1, depth+1)
+		}
+	}
+	choose(0, 0)
+	return result
+}
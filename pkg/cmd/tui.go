@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"guysports/playerstats/pkg/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"golang.org/x/term"
+)
+
+const (
+	tuiPlayers = iota
+	tuiFixtures
+	tuiWatchlist
+)
+
+var (
+	tuiTabs       = []string{"Players", "Fixtures", "Watchlist"}
+	tuiSortFields = []string{"total_points", "last_5_avg", "projected", "cost", "points_per_million", "name"}
+	positionAbbr  = map[int]string{1: "GK", 2: "DEF", 3: "MID", 4: "FWD"}
+)
+
+type (
+	Tui struct {
+		Weeks     int    `help:"number of upcoming game weeks to show fixtures and project points for" default:"5"`
+		Watchlist string `help:"file the watchlist is kept in, default is watchlist.json in the cache directory"`
+	}
+
+	// tuiState is everything shown on the screen and the position in each view
+	tuiState struct {
+		globals   *Globals
+		players   []types.Player
+		visible   []types.Player
+		fixtures  []string
+		tab       int
+		cursor    int
+		offset    int
+		search    string
+		searching bool
+		sortIndex int
+		ascending bool
+		detail    bool
+		info      []string
+		scroll    int
+		watched   map[int]bool
+		watchFile string
+		message   string
+		width     int
+		height    int
+	}
+)
+
+func (t *Tui) Run(globals *Globals) error {
+	players, matchweeks, err := loadPlayerData(globals, t.Weeks)
+	if err != nil {
+		return err
+	}
+	squadIds := []int{}
+	for id := range globals.SquadMap {
+		squadIds = append(squadIds, id)
+	}
+	gws := upcomingGameweeks(matchweeks, t.Weeks)
+	grid := fixtureGrid(squadRuns(matchweeks, gws, squadIds, globals.SquadMap), gws, globals.SquadMap, false).Render()
+
+	state := &tuiState{
+		globals:   globals,
+		players:   players,
+		fixtures:  strings.Split(grid, "\n"),
+		watched:   map[int]bool{},
+		watchFile: t.Watchlist,
+	}
+	if state.watchFile == "" {
+		state.watchFile = filepath.Join(globals.CacheDir, "watchlist.json")
+	}
+	if err := state.loadWatchlist(); err != nil {
+		return err
+	}
+	state.refresh()
+
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	buf := make([]byte, 64)
+	for {
+		state.width, state.height = terminalSize()
+		state.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			if !state.handle(key) {
+				return nil
+			}
+		}
+	}
+}
+
+// enterRawMode switches the terminal to read single key presses on an alternate screen, returning
+// a function that puts it back as it was
+func enterRawMode() (func(), error) {
+	saved, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("tui needs an interactive terminal: %v", err)
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return func() {
+		fmt.Print("\x1b[0m\x1b[?25h\x1b[?1049l")
+		term.Restore(int(os.Stdin.Fd()), saved)
+	}, nil
+}
+
+// terminalSize returns the width and height of the terminal, assuming 80 by 24 if it is not known
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// escapeKeys name the keys sent as an escape sequence by their final byte, and by their number for
+// sequences ending in ~
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left", "H": "home", "F": "end",
+	"1~": "home", "7~": "home", "4~": "end", "8~": "end", "3~": "delete", "5~": "pgup", "6~": "pgdn",
+}
+
+// parseKeys names the keys in a read from the terminal, e.g. "up" for an arrow key, or the character
+// typed. Escape sequences are consumed whole and any the tui has no use for are dropped.
+func parseKeys(input []byte) []string {
+	keys := []string{}
+	for len(input) > 0 {
+		if input[0] == 27 {
+			if len(input) == 1 {
+				return append(keys, "esc")
+			}
+			switch input[1] {
+			case 'O':
+				// ESC O and one final byte
+				if len(input) < 3 {
+					return keys
+				}
+				if key, ok := escapeKeys[string(input[2])]; ok {
+					keys = append(keys, key)
+				}
+				input = input[3:]
+				continue
+			case '[':
+				// ESC [, parameter and intermediate bytes, then a final byte from @ to ~
+				end := 2
+				for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+					end++
+				}
+				if end == len(input) {
+					return keys
+				}
+				name := string(input[end])
+				if name == "~" {
+					// Modifiers such as Ctrl follow the key number after a semicolon
+					name = strings.SplitN(string(input[2:end]), ";", 2)[0] + name
+				}
+				if key, ok := escapeKeys[name]; ok {
+					keys = append(keys, key)
+				}
+				input = input[end+1:]
+				continue
+			}
+			keys = append(keys, "esc")
+			input = input[1:]
+			continue
+		}
+		switch input[0] {
+		case 3:
+			keys = append(keys, "quit")
+		case 9:
+			keys = append(keys, "tab")
+		case 10, 13:
+			keys = append(keys, "enter")
+		case 8, 127:
+			keys = append(keys, "backspace")
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, string(r))
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// handle acts on a key press, returning false when the user quits
+func (s *tuiState) handle(key string) bool {
+	s.message = ""
+	if key == "quit" {
+		return false
+	}
+	if s.searching {
+		switch key {
+		case "enter":
+			s.searching = false
+		case "esc":
+			s.searching = false
+			s.search = ""
+		case "backspace":
+			if s.search != "" {
+				_, size := utf8.DecodeLastRuneInString(s.search)
+				s.search = s.search[:len(s.search)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				s.search += key
+			}
+		}
+		s.cursor, s.offset = 0, 0
+		s.refresh()
+		return true
+	}
+
+	switch key {
+	case "q":
+		return false
+	case "tab":
+		s.switchTab((s.tab + 1) % len(tuiTabs))
+		return true
+	case "1", "2", "3":
+		s.switchTab(int(key[0] - '1'))
+		return true
+	}
+
+	page := s.height - 4
+	if page < 1 {
+		page = 1
+	}
+	if s.detail || s.tab == tuiFixtures {
+		switch key {
+		case "up", "k":
+			s.scroll--
+		case "down", "j":
+			s.scroll++
+		case "pgup":
+			s.scroll -= page
+		case "pgdn":
+			s.scroll += page
+		case "home", "g":
+			s.scroll = 0
+		case "esc", "left", "backspace", "enter":
+			s.detail = false
+		case "w":
+			s.toggleWatch()
+		}
+		if s.scroll < 0 {
+			s.scroll = 0
+		}
+		return true
+	}
+
+	switch key {
+	case "up", "k":
+		s.cursor--
+	case "down", "j":
+		s.cursor++
+	case "pgup":
+		s.cursor -= page
+	case "pgdn":
+		s.cursor += page
+	case "home", "g":
+		s.cursor = 0
+	case "end", "G":
+		s.cursor = len(s.visible) - 1
+	case "enter", "right":
+		if len(s.visible) > 0 {
+			player := s.visible[s.cursor]
+			info := playerInfoTable(player, s.globals.CompetitionMap, s.globals.SquadMap, true, true).Render()
+			s.detail, s.info, s.scroll = true, strings.Split(info, "\n"), 0
+		}
+	case "/":
+		s.searching = true
+	case "esc":
+		s.search = ""
+		s.refresh()
+	case "s":
+		s.sortIndex = (s.sortIndex + 1) % len(tuiSortFields)
+		s.refresh()
+	case "r":
+		s.ascending = !s.ascending
+		s.refresh()
+	case "w":
+		s.toggleWatch()
+	}
+	if s.cursor >= len(s.visible) {
+		s.cursor = len(s.visible) - 1
+	}
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+	return true
+}
+
+func (s *tuiState) switchTab(tab int) {
+	s.tab, s.detail, s.scroll, s.cursor, s.offset = tab, false, 0, 0, 0
+	s.refresh()
+}
+
+// refresh selects the players for the list from the tab and search, in the chosen order
+func (s *tuiState) refresh() {
+	search := strings.ToLower(s.search)
+	s.visible = []types.Player{}
+	for _, player := range s.players {
+		if s.tab == tuiWatchlist && !s.watched[player.Id] {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(playerName(player)+" "+player.Team), search) {
+			continue
+		}
+		s.visible = append(s.visible, player)
+	}
+	direction := ":desc"
+	if s.ascending {
+		direction = ":asc"
+	}
+	sortKeys, _ := parseSortKeys([]string{tuiSortFields[s.sortIndex] + direction})
+	sortPlayers(s.visible, sortKeys)
+	if s.cursor >= len(s.visible) {
+		s.cursor = 0
+	}
+}
+
+func (s *tuiState) selected() (types.Player, bool) {
+	if s.tab == tuiFixtures || s.cursor >= len(s.visible) {
+		return types.Player{}, false
+	}
+	return s.visible[s.cursor], true
+}
+
+// toggleWatch adds the selected player to the watchlist or removes them, saving it straight away
+func (s *tuiState) toggleWatch() {
+	player, ok := s.selected()
+	if !ok {
+		return
+	}
+	if s.watched[player.Id] {
+		delete(s.watched, player.Id)
+		s.message = fmt.Sprintf("removed %s from the watchlist", playerName(player))
+	} else {
+		s.watched[player.Id] = true
+		s.message = fmt.Sprintf("added %s to the watchlist", playerName(player))
+	}
+	if err := s.saveWatchlist(); err != nil {
+		s.message = fmt.Sprintf("saving the watchlist failed: %v", err)
+	}
+	if s.tab == tuiWatchlist && !s.detail {
+		s.refresh()
+	}
+}
+
+func (s *tuiState) loadWatchlist() error {
+	data, err := ioutil.ReadFile(s.watchFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ids := []int{}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return fmt.Errorf("%s: %v", s.watchFile, err)
+	}
+	for _, id := range ids {
+		s.watched[id] = true
+	}
+	return nil
+}
+
+func (s *tuiState) saveWatchlist() error {
+	ids := []int{}
+	for id := range s.watched {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.watchFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.watchFile, data, 0644)
+}
+
+// draw writes the whole screen, the tab bar, the current view and a status line with the keys
+func (s *tuiState) draw() {
+	tabs := []string{}
+	for i, name := range tuiTabs {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if i == s.tab {
+			label = "\x1b[7m" + label + "\x1b[0m"
+		}
+		tabs = append(tabs, label)
+	}
+	lines := []string{strings.Join(tabs, " ")}
+
+	body := s.height - 2
+	var status string
+	switch {
+	case s.tab == tuiFixtures:
+		lines = append(lines, scrollLines(s.fixtures, &s.scroll, body)...)
+		status = "up/down scroll  tab switch  q quit"
+	case s.detail:
+		lines = append(lines, scrollLines(s.info, &s.scroll, body)...)
+		status = "up/down scroll  w watch  esc back  q quit"
+	default:
+		lines = append(lines, s.listLines(body)...)
+		direction := "desc"
+		if s.ascending {
+			direction = "asc"
+		}
+		status = fmt.Sprintf("%d players  sort %s %s", len(s.visible), tuiSortFields[s.sortIndex], direction)
+		if s.search != "" || s.searching {
+			status += fmt.Sprintf("  search %q", s.search)
+		}
+		status += "  / search  s sort  r reverse  w watch  enter details  tab switch  q quit"
+		if s.searching {
+			status = fmt.Sprintf("search: %s_  enter done  esc clear", s.search)
+		}
+	}
+	if s.message != "" {
+		status = s.message
+	}
+	for len(lines) < s.height-1 {
+		lines = append(lines, "")
+	}
+
+	var out strings.Builder
+	out.WriteString("\x1b[H")
+	for _, line := range lines {
+		out.WriteString(text.Trim(line, s.width))
+		out.WriteString("\x1b[0m\x1b[K\r\n")
+	}
+	out.WriteString("\x1b[7m" + text.Pad(text.Trim(status, s.width), s.width, ' ') + "\x1b[0m")
+	fmt.Print(out.String())
+}
+
+// listLines shows a page of the players around the cursor, marking the watched players with a star
+func (s *tuiState) listLines(height int) []string {
+	lines := []string{fmt.Sprintf("  %-3s %-26s %-4s %8s %5s %6s %6s", "Pos", "Name", "Team", "Cost", "Pts", "L5", "Proj")}
+	rows := height - 1
+	if rows < 1 {
+		rows = 1
+	}
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+rows {
+		s.offset = s.cursor - rows + 1
+	}
+	for i := s.offset; i < len(s.visible) && i < s.offset+rows; i++ {
+		player := s.visible[i]
+		mark := " "
+		if s.watched[player.Id] {
+			mark = "*"
+		}
+		line := fmt.Sprintf("%s %-3s %-26s %-4s %8s %5d %6.2f %6.2f", mark, positionAbbr[player.Positions[0]],
+			text.Trim(playerName(player), 26), player.TeamAbbr, formatCost(player.Cost),
+			player.InPlayStats.TotalPoints, player.InPlayStats.Last5Avg, player.Projected)
+		if i == s.cursor {
+			line = "\x1b[7m" + text.Pad(line, s.width, ' ') + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(s.visible) == 0 {
+		lines = append(lines, "  no players")
+	}
+	return lines
+}
+
+// scrollLines returns the lines that fit in height from the scroll position, keeping it in range
+func scrollLines(lines []string, scroll *int, height int) []string {
+	if height < 1 {
+		height = 1
+	}
+	if *scroll > len(lines)-height {
+		*scroll = len(lines) - height
+	}
+	if *scroll < 0 {
+		*scroll = 0
+	}
+	end := *scroll + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return lines[*scroll:end]
+}
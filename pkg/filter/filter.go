@@ -0,0 +1,380 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kind is the type of value a field holds
+type Kind int
+
+const (
+	Number Kind = iota
+	String
+)
+
+type (
+	// Record returns the value of a named field, either a float64 or a string
+	Record func(field string) interface{}
+
+	// Expression is a parsed filter such as `position == "midfielder" && cost <= 7.5`
+	Expression struct {
+		source string
+		root   *node
+	}
+
+	node struct {
+		op    string
+		left  *node
+		right *node
+		num   float64
+		str   string
+		kind  Kind
+	}
+
+	token struct {
+		kind  string
+		value string
+		pos   int
+	}
+
+	parser struct {
+		tokens []token
+		pos    int
+		fields map[string]string
+		kinds  map[string]Kind
+	}
+)
+
+// Normalize folds a field name so that last_5_avg, Last5Avg and last5avg are the same field
+func Normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// Parse compiles an expression, checking every field it uses exists and is compared with a value of the same kind
+func Parse(source string, fields map[string]Kind) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{
+		tokens: tokens,
+		fields: map[string]string{},
+		kinds:  fields,
+	}
+	for name := range fields {
+		p.fields[Normalize(name)] = name
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos+1)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// ResolveLiterals rewrites every text value compared against the named field, so that
+// aliases such as "Spurs" can be turned into the value the field actually holds
+func (e *Expression) ResolveLiterals(field string, resolve func(value string) string) {
+	e.root.resolveLiterals(field, resolve)
+}
+
+func (n *node) resolveLiterals(field string, resolve func(value string) string) {
+	switch n.op {
+	case "||", "&&":
+		n.left.resolveLiterals(field, resolve)
+		n.right.resolveLiterals(field, resolve)
+		return
+	case "!":
+		n.left.resolveLiterals(field, resolve)
+		return
+	}
+	if n.kind != String {
+		return
+	}
+	if n.left.op == "field" && n.left.str == field && n.right.op == "string" {
+		n.right.str = resolve(n.right.str)
+	}
+	if n.right.op == "field" && n.right.str == field && n.left.op == "string" {
+		n.left.str = resolve(n.left.str)
+	}
+}
+
+// Match reports whether the record satisfies the expression
+func (e *Expression) Match(record Record) bool {
+	return e.root.match(record)
+}
+
+func (n *node) match(record Record) bool {
+	switch n.op {
+	case "||":
+		return n.left.match(record) || n.right.match(record)
+	case "&&":
+		return n.left.match(record) && n.right.match(record)
+	case "!":
+		return !n.left.match(record)
+	}
+
+	// Comparison of two operands of the same kind
+	if n.kind == String {
+		left := strings.ToLower(n.left.stringValue(record))
+		right := strings.ToLower(n.right.stringValue(record))
+		switch n.op {
+		case "==":
+			return left == right
+		case "!=":
+			return left != right
+		case "<":
+			return left < right
+		case "<=":
+			return left <= right
+		case ">":
+			return left > right
+		case ">=":
+			return left >= right
+		}
+		return false
+	}
+	left := n.left.numberValue(record)
+	right := n.right.numberValue(record)
+	switch n.op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	}
+	return false
+}
+
+func (n *node) stringValue(record Record) string {
+	if n.op == "field" {
+		value, _ := record(n.str).(string)
+		return value
+	}
+	return n.str
+}
+
+func (n *node) numberValue(record Record) float64 {
+	if n.op == "field" {
+		switch value := record(n.str).(type) {
+		case float64:
+			return value
+		case int:
+			return float64(value)
+		case float32:
+			return float64(value)
+		}
+		return 0
+	}
+	return n.num
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	switch p.peek().kind {
+	case "!":
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: "!", left: operand}, nil
+	case "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != ")" {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %s", tok.pos+1, describe(tok))
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (*node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.next()
+	if tok.kind != "cmp" {
+		return nil, fmt.Errorf("expected comparison operator at position %d, got %s", tok.pos+1, describe(tok))
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.kind != right.kind {
+		return nil, fmt.Errorf("cannot compare %s with %s at position %d", kindName(left.kind), kindName(right.kind), tok.pos+1)
+	}
+	return &node{op: tok.value, left: left, right: right, kind: left.kind}, nil
+}
+
+func (p *parser) parseOperand() (*node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "number":
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.value, tok.pos+1)
+		}
+		return &node{op: "number", num: value, kind: Number}, nil
+	case "string":
+		return &node{op: "string", str: tok.value, kind: String}, nil
+	case "ident":
+		name, ok := p.fields[Normalize(tok.value)]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", tok.value, tok.pos+1)
+		}
+		return &node{op: "field", str: name, kind: p.kinds[name]}, nil
+	}
+	return nil, fmt.Errorf("expected field or value at position %d, got %s", tok.pos+1, describe(tok))
+}
+
+func lex(source string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{kind: string(r), value: string(r), pos: i})
+			i++
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, fmt.Errorf("expected %q at position %d", string([]rune{r, r}), i+1)
+			}
+			tokens = append(tokens, token{kind: string([]rune{r, r}), value: string([]rune{r, r}), pos: i})
+			i += 2
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			switch op {
+			case "!":
+				tokens = append(tokens, token{kind: "!", value: op, pos: i})
+			case "=":
+				// Accept a single equals as equality
+				tokens = append(tokens, token{kind: "cmp", value: "==", pos: i})
+			default:
+				tokens = append(tokens, token{kind: "cmp", value: op, pos: i})
+			}
+			i += len(op)
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: "string", value: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '.' || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: "number", value: string(runes[i:end]), pos: i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, token{kind: "&&", value: word, pos: i})
+			case "or":
+				tokens = append(tokens, token{kind: "||", value: word, pos: i})
+			case "not":
+				tokens = append(tokens, token{kind: "!", value: word, pos: i})
+			default:
+				tokens = append(tokens, token{kind: "ident", value: word, pos: i})
+			}
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", string(r), i+1)
+		}
+	}
+	return append(tokens, token{kind: "eof", pos: len(runes)}), nil
+}
+
+func describe(tok token) string {
+	if tok.kind == "eof" {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", tok.value)
+}
+
+func kindName(kind Kind) string {
+	if kind == String {
+		return "text"
+	}
+	return "number"
+}
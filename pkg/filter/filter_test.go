@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+var testFields = map[string]Kind{
+	"position":     String,
+	"team":         String,
+	"cost":         Number,
+	"total_points": Number,
+	"last_5_avg":   Number,
+}
+
+func testRecord(values map[string]interface{}) Record {
+	return func(field string) interface{} {
+		return values[field]
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"", "expected field or value at position 1"},
+		{"cost <=", "expected field or value at position 8"},
+		{"cost 7.5", "expected comparison operator at position 6"},
+		{"price > 5", `unknown field "price" at position 1`},
+		{`cost == "five"`, "cannot compare number with text at position 6"},
+		{`position == 3`, "cannot compare text with number at position 10"},
+		{"cost > 5 & team == 'Arsenal'", `expected "&&" at position 10`},
+		{`team == "Arsenal`, "unterminated string starting at position 9"},
+		{"(cost > 5", `expected ")" at position 10`},
+		{"cost > 5)", `unexpected ")" at position 9`},
+		{"cost > 5 # 2", `unexpected character "#" at position 10`},
+		{"cost > 1.2.3", `invalid number "1.2.3" at position 8`},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.source, testFields)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error %q", test.source, test.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Parse(%q) error = %q, want %q", test.source, err, test.err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	salah := testRecord(map[string]interface{}{
+		"position":     "midfielder",
+		"team":         "Liverpool",
+		"cost":         12.5,
+		"total_points": 42.0,
+		"last_5_avg":   float32(8.5),
+	})
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"cost > 12", true},
+		{"cost <= 12", false},
+		{"cost == 12.5 && total_points >= 42", true},
+		{"cost < 5 || total_points > 40", true},
+		{"cost < 5 or total_points < 40", false},
+		{"!(cost < 5)", true},
+		{"not cost > 5", false},
+		{"cost > 5 and not total_points == 42", false},
+		{`position == "Midfielder"`, true},
+		{`position = 'midfielder'`, true},
+		{`team != "Arsenal"`, true},
+		{`"Liverpool" == team`, true},
+		{"12.5 == cost", true},
+		{"Last5Avg > 8", true},
+		{"cost > -1", true},
+		{"cost > 5 || cost < 1 && total_points > 100", true},
+		{"(cost > 5 || cost < 1) && total_points > 100", false},
+	}
+	for _, test := range tests {
+		expression, err := Parse(test.source, testFields)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", test.source, err)
+			continue
+		}
+		if got := expression.Match(salah); got != test.want {
+			t.Errorf("%q matched %v, want %v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestResolveLiterals(t *testing.T) {
+	aliases := map[string]string{"spurs": "Tottenham Hotspur"}
+	resolve := func(value string) string {
+		if name, ok := aliases[strings.ToLower(value)]; ok {
+			return name
+		}
+		return value
+	}
+	spurs := testRecord(map[string]interface{}{"team": "Tottenham Hotspur", "position": "spurs"})
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`team == "Spurs"`, true},
+		{`"spurs" == team`, true},
+		{`team != "Spurs"`, false},
+		{`position == "Spurs"`, true},
+		{`team == "Spurs" && !(team == "Arsenal")`, true},
+	}
+	for _, test := range tests {
+		expression, err := Parse(test.source, testFields)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", test.source, err)
+		}
+		expression.ResolveLiterals("team", resolve)
+		if got := expression.Match(spurs); got != test.want {
+			t.Errorf("%q matched %v, want %v", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package helper
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotTimeFormat = "20060102T150405Z"
+	metaFile           = "meta.json"
+)
+
+type (
+	// CacheOptions control how remote sources are stored and reused between runs
+	CacheOptions struct {
+		Dir     string
+		MaxAge  time.Duration
+		Offline bool
+	}
+
+	// cacheMeta records the validators and latest snapshot for a single source
+	cacheMeta struct {
+		URI          string    `json:"uri"`
+		ETag         string    `json:"etag"`
+		LastModified string    `json:"last_modified"`
+		Snapshot     string    `json:"snapshot"`
+		Checked      time.Time `json:"checked"`
+	}
+
+	// Snapshot is a single timestamped copy of a source held in the cache
+	Snapshot struct {
+		URI     string
+		Path    string
+		Fetched time.Time
+	}
+)
+
+var cacheOptions CacheOptions
+
+// ConfigureCache sets the cache used by GetJSON for remote sources
+func ConfigureCache(opts CacheOptions) {
+	cacheOptions = opts
+}
+
+// sourceDir returns the cache directory holding the snapshots for a URI
+func sourceDir(uri string) string {
+	sum := sha1.Sum([]byte(uri))
+	return filepath.Join(cacheOptions.Dir, fmt.Sprintf("%s-%x", path.Base(uri), sum[:4]))
+}
+
+func readMeta(dir string) (*cacheMeta, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, metaFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := cacheMeta{}
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("corrupt cache metadata in %s: %v", dir, err)
+	}
+	return &meta, nil
+}
+
+func writeMeta(dir string, meta *cacheMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, metaFile), data, 0644)
+}
+
+// getCachedJSON serves a remote source from the cache, revalidating it with the server once it is older than MaxAge
+func getCachedJSON(uri string) ([]byte, error) {
+	dir := sourceDir(uri)
+	meta, err := readMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheOptions.Offline {
+		if meta == nil || meta.Snapshot == "" {
+			return nil, fmt.Errorf("offline: %q has never been fetched, run once without --offline to populate the cache", uri)
+		}
+		return ioutil.ReadFile(filepath.Join(dir, meta.Snapshot))
+	}
+
+	if meta != nil && meta.Snapshot != "" && time.Since(meta.Checked) < cacheOptions.MaxAge {
+		return ioutil.ReadFile(filepath.Join(dir, meta.Snapshot))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil && meta.Snapshot != "" {
+		// Conditionally request the source so unchanged data is not downloaded again
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch URL %q: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	now := time.Now().UTC()
+	if resp.StatusCode == http.StatusNotModified && meta != nil && meta.Snapshot != "" {
+		meta.Checked = now
+		if err = writeMeta(dir, meta); err != nil {
+			return nil, err
+		}
+		return ioutil.ReadFile(filepath.Join(dir, meta.Snapshot))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http GET status: %s", resp.Status)
+	}
+	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable read response body %s", err.Error())
+	}
+
+	// Store the new data as a timestamped snapshot and point the metadata at it
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create cache directory: %v", err)
+	}
+	snapshot := now.Format(snapshotTimeFormat) + ".json"
+	if err = ioutil.WriteFile(filepath.Join(dir, snapshot), bytes, 0644); err != nil {
+		return nil, fmt.Errorf("unable to write cache snapshot: %v", err)
+	}
+	meta = &cacheMeta{
+		URI:          uri,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Snapshot:     snapshot,
+		Checked:      now,
+	}
+	if err = writeMeta(dir, meta); err != nil {
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// ListSnapshots returns the cached snapshots of a URI, oldest first
+func ListSnapshots(uri string) ([]Snapshot, error) {
+	if cacheOptions.Dir == "" {
+		return nil, fmt.Errorf("no cache directory configured")
+	}
+	dir := sourceDir(uri)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []Snapshot{}
+	for _, file := range files {
+		name := file.Name()
+		if name == metaFile || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		fetched, err := time.Parse(snapshotTimeFormat, strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			URI:     uri,
+			Path:    filepath.Join(dir, name),
+			Fetched: fetched,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Fetched.Before(snapshots[j].Fetched)
+	})
+	return snapshots, nil
+}
+
+// FindSnapshot resolves a reference to a cached snapshot of a URI. The reference
+// may be "latest", "previous", a negative offset from the latest such as "-2",
+// or a date/time in which case the last snapshot fetched at or before it is used
+func FindSnapshot(uri string, ref string) (*Snapshot, error) {
+	snapshots, err := ListSnapshots(uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots of %q have been fetched", uri)
+	}
+
+	index := -1
+	switch {
+	case ref == "" || ref == "latest":
+		index = len(snapshots) - 1
+	case ref == "previous":
+		index = len(snapshots) - 2
+	case strings.HasPrefix(ref, "-"):
+		offset, err := strconv.Atoi(ref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot offset %q", ref)
+		}
+		index = len(snapshots) - 1 + offset
+	default:
+		at, err := parseSnapshotTime(ref)
+		if err != nil {
+			return nil, err
+		}
+		for i, snapshot := range snapshots {
+			if !snapshot.Fetched.After(at) {
+				index = i
+			}
+		}
+	}
+	if index < 0 || index >= len(snapshots) {
+		return nil, fmt.Errorf("no snapshot of %q matches %q, %d available", uri, ref, len(snapshots))
+	}
+	return &snapshots[index], nil
+}
+
+// parseSnapshotTime accepts a date, or a date and time, treating a bare date as the end of that day.
+// Times without a zone are local, as snapshots are listed in local time
+func parseSnapshotTime(ref string) (time.Time, error) {
+	if at, err := time.ParseInLocation("2006-01-02", ref, time.Local); err == nil {
+		return at.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if at, err := time.ParseInLocation(layout, ref, time.Local); err == nil {
+			return at, nil
+		}
+	}
+	if at, err := time.Parse(snapshotTimeFormat, ref); err == nil {
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised snapshot reference %q, use latest, previous, -N or a date", ref)
+}
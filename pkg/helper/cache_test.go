@@ -0,0 +1,108 @@
+package helper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSource serves a body with an ETag, answering a matching If-None-Match with 304, and counts
+// the requests and full responses
+type testSource struct {
+	body     string
+	etag     string
+	requests int
+	sent     int
+}
+
+func (s *testSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests++
+	w.Header().Set("ETag", s.etag)
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.sent++
+	w.Write([]byte(s.body))
+}
+
+func TestGetCachedJSON(t *testing.T) {
+	source := &testSource{body: `{"round": 1}`, etag: `"1"`}
+	server := httptest.NewServer(source)
+	defer server.Close()
+	defer ConfigureCache(CacheOptions{})
+	uri := server.URL + "/players.json"
+
+	steps := []struct {
+		name     string
+		maxAge   time.Duration
+		offline  bool
+		change   string
+		want     string
+		requests int
+		sent     int
+	}{
+		{"first fetch downloads the source", time.Hour, false, "", `{"round": 1}`, 1, 1},
+		{"fresh cache is served without a request", time.Hour, false, "", `{"round": 1}`, 1, 1},
+		{"stale cache is revalidated", 0, false, "", `{"round": 1}`, 2, 1},
+		{"changed source is downloaded again", 0, false, `{"round": 2}`, `{"round": 2}`, 3, 2},
+		{"offline serves the latest snapshot", 0, true, `{"round": 3}`, `{"round": 2}`, 3, 2},
+	}
+	dir := t.TempDir()
+	for _, step := range steps {
+		ConfigureCache(CacheOptions{Dir: dir, MaxAge: step.maxAge, Offline: step.offline})
+		if step.change != "" {
+			source.body = step.change
+			source.etag = `"` + step.change + `"`
+		}
+		data, err := getCachedJSON(uri)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if string(data) != step.want {
+			t.Errorf("%s: got %s, want %s", step.name, data, step.want)
+		}
+		if source.requests != step.requests || source.sent != step.sent {
+			t.Errorf("%s: %d requests and %d full responses, want %d and %d", step.name, source.requests, source.sent, step.requests, step.sent)
+		}
+	}
+
+	ConfigureCache(CacheOptions{Dir: t.TempDir(), Offline: true})
+	if _, err := getCachedJSON(uri); err == nil || !strings.Contains(err.Error(), "has never been fetched") {
+		t.Errorf("offline without a snapshot gave error %v, want it to say the source was never fetched", err)
+	}
+}
+
+func TestParseSnapshotTime(t *testing.T) {
+	// Snapshots are listed in local time, so times without a zone have to be read in it too
+	local := time.Local
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		ref  string
+		want time.Time
+	}{
+		{"2022-08-03", time.Date(2022, 8, 3, 21, 59, 59, 0, time.UTC)},
+		{"2022-08-03 15:04", time.Date(2022, 8, 3, 13, 4, 0, 0, time.UTC)},
+		{"2022-08-03 15:04:05", time.Date(2022, 8, 3, 13, 4, 5, 0, time.UTC)},
+		{"2022-08-03T15:04", time.Date(2022, 8, 3, 13, 4, 0, 0, time.UTC)},
+		{"2022-08-03T15:04:05Z", time.Date(2022, 8, 3, 15, 4, 5, 0, time.UTC)},
+		{"20220803T150405Z", time.Date(2022, 8, 3, 15, 4, 5, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		at, err := parseSnapshotTime(test.ref)
+		if err != nil {
+			t.Errorf("%s: %v", test.ref, err)
+			continue
+		}
+		if !at.Equal(test.want) {
+			t.Errorf("%s: parsed as %v, want %v", test.ref, at.UTC(), test.want)
+		}
+	}
+	if _, err := parseSnapshotTime("last week"); err == nil {
+		t.Errorf("last week parsed as a snapshot time")
+	}
+}
@@ -9,6 +9,12 @@ import (
 
 func GetJSON(uri string) ([]byte, error) {
 	if strings.HasPrefix(uri, "https") {
+		if cacheOptions.Dir != "" {
+			return getCachedJSON(uri)
+		}
+		if cacheOptions.Offline {
+			return nil, fmt.Errorf("offline: no cache directory configured to serve %q", uri)
+		}
 		// Read data from URI
 		resp, err := http.Get(uri)
 		if err != nil {
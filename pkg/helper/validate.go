@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// FeedReport records the problems found while decoding a feed
+	FeedReport struct {
+		Source   string
+		Records  int
+		Skipped  int
+		Problems []string
+		Unknown  map[string]int
+		Missing  map[string]int
+		// Inconsistencies are disagreements with other feeds found once the records are decoded
+		Inconsistencies []string
+	}
+)
+
+var feedReports = []*FeedReport{}
+
+// DecodeFeed decodes a JSON array feed into records, which must be a pointer to a slice of structs.
+// Records that cannot be decoded, are missing a required field or fail validate are skipped and
+// reported, while a feed that is not a valid JSON array is an error giving the offset of the problem
+func DecodeFeed(source string, data []byte, records interface{}, required []string, validate func(record interface{}) error) error {
+	slice := reflect.ValueOf(records).Elem()
+	elemType := slice.Type().Elem()
+	known := jsonFieldNames(elemType)
+	report := &FeedReport{
+		Source:  source,
+		Unknown: map[string]int{},
+		Missing: map[string]int{},
+	}
+	feedReports = append(feedReports, report)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	tok, err := decoder.Token()
+	if err != nil {
+		return decodeError(source, decoder.InputOffset(), err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("%s: expected a JSON array at offset 0, got %v", source, tok)
+	}
+
+	for decoder.More() {
+		offset := decoder.InputOffset()
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			return decodeError(source, decoder.InputOffset(), err)
+		}
+		report.Records++
+		index := report.Records - 1
+
+		// Check the fields present against those expected
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			report.skip("record %d at offset %d is not an object", index, offset)
+			continue
+		}
+		for name := range fields {
+			if !known[name] {
+				report.Unknown[name]++
+			}
+		}
+		missing := []string{}
+		for _, name := range required {
+			if value, ok := fields[name]; !ok || string(value) == "null" {
+				missing = append(missing, name)
+				report.Missing[name]++
+			}
+		}
+		if len(missing) > 0 {
+			report.skip("record %d at offset %d is missing required %s", index, offset, strings.Join(missing, ", "))
+			continue
+		}
+
+		record := reflect.New(elemType)
+		if err := json.Unmarshal(raw, record.Interface()); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				report.skip("record %d at offset %d: field %s should be %s, got %s", index, offset+typeErr.Offset, typeErr.Field, typeErr.Type, typeErr.Value)
+			} else {
+				report.skip("record %d at offset %d: %v", index, offset, err)
+			}
+			continue
+		}
+		if validate != nil {
+			if err := validate(record.Interface()); err != nil {
+				report.skip("record %d at offset %d: %v", index, offset, err)
+				continue
+			}
+		}
+		slice.Set(reflect.Append(slice, record.Elem()))
+	}
+	if _, err := decoder.Token(); err != nil {
+		return decodeError(source, decoder.InputOffset(), err)
+	}
+	return nil
+}
+
+// ResetFeedReports forgets the reports of earlier decodes, for when the feeds are loaded again
+func ResetFeedReports() {
+	feedReports = []*FeedReport{}
+}
+
+// ReportInconsistency records a disagreement between a decoded feed and the other feeds
+func ReportInconsistency(source string, format string, args ...interface{}) {
+	for _, report := range feedReports {
+		if report.Source == source {
+			report.Inconsistencies = append(report.Inconsistencies, fmt.Sprintf(format, args...))
+			return
+		}
+	}
+	feedReports = append(feedReports, &FeedReport{
+		Source:          source,
+		Unknown:         map[string]int{},
+		Missing:         map[string]int{},
+		Inconsistencies: []string{fmt.Sprintf(format, args...)},
+	})
+}
+
+func (r *FeedReport) skip(format string, args ...interface{}) {
+	r.Skipped++
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func decodeError(source string, offset int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%s: feed is truncated at offset %d", source, offset)
+	}
+	return fmt.Errorf("%s: invalid JSON at offset %d: %v", source, offset, err)
+}
+
+// jsonFieldNames returns the json names of the fields of a struct type
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// FeedSummary describes the problems found in every decoded feed, including unknown fields if
+// verbose is set, and reports whether any records had to be skipped
+func FeedSummary(verbose bool) (string, bool) {
+	lines := []string{}
+	failed := false
+	for _, report := range feedReports {
+		if report.Skipped > 0 {
+			failed = true
+			lines = append(lines, fmt.Sprintf("%s: skipped %d of %d records", report.Source, report.Skipped, report.Records))
+			for _, problem := range report.Problems {
+				lines = append(lines, "  "+problem)
+			}
+		}
+		if !verbose {
+			if len(report.Inconsistencies) > 0 {
+				lines = append(lines, fmt.Sprintf("%s: %d inconsistencies with other feeds, use --validate to list them", report.Source, len(report.Inconsistencies)))
+			}
+			continue
+		}
+		if report.Skipped == 0 {
+			lines = append(lines, fmt.Sprintf("%s: %d records valid", report.Source, report.Records))
+		}
+		for _, inconsistency := range report.Inconsistencies {
+			lines = append(lines, "  "+inconsistency)
+		}
+		for _, name := range sortedKeys(report.Unknown) {
+			lines = append(lines, fmt.Sprintf("  unknown field %q in %d records", name, report.Unknown[name]))
+		}
+		for _, name := range sortedKeys(report.Missing) {
+			lines = append(lines, fmt.Sprintf("  required field %q missing in %d records", name, report.Missing[name]))
+		}
+	}
+	return strings.Join(lines, "\n"), failed
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := []string{}
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
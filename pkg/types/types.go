@@ -1,40 +1,6 @@
 package types
 
 var (
-	Teams = map[int]string{
-		11:  "Everton",
-		8:   "Chelsea",
-		1:   "Manchester United",
-		56:  "Sunderland",
-		110: "Stoke",
-		31:  "Crystal Palace",
-		21:  "West Ham",
-		35:  "WBA",
-		88:  "Hull City",
-		25:  "Middlesborough",
-		3:   "Arsenal",
-		80:  "Swansea City",
-		13:  "Leicester City",
-		43:  "Manchester City",
-		91:  "AFC Bournemouth",
-		14:  "Liverpool",
-		90:  "Burnley",
-		57:  "Watford",
-		20:  "Southampton",
-		6:   "Tottenham Hotspur",
-		36:  "Brighton",
-		4:   "Newcastle",
-		38:  "Huddersfield",
-		97:  "Cardiff City",
-		39:  "Wolves",
-		7:   "Aston Villa",
-		45:  "Norwich",
-		49:  "Sheffield United",
-		2:   "Leeds United",
-		54:  "Fulham",
-		94:  "Brentford",
-	}
-
 	Position = map[int]string{
 		1: "goalkeeper",
 		2: "defender",
@@ -56,9 +22,33 @@ type (
 		Locked         int      `json:"locked"`
 		InPlayEPLStats EPLStats `json:"epl_stats"`
 		Team           string
+		TeamShort      string
+		TeamAbbr       string
 		Job            string
 		CostDisp       string
 		Matches        map[string]Match
+		Projected      float64
+		Projections    []ProjectedFixture
+		// GwFixtures is the number of matches the player's squad plays in each game week
+		GwFixtures       map[int]int
+		SpecialGameweeks []SpecialGameweek
+	}
+
+	// SpecialGameweek is a game week in which a squad plays no match or more than one
+	SpecialGameweek struct {
+		Gw           int      `json:"gw"`
+		Kind         string   `json:"kind"`
+		Matches      int      `json:"matches"`
+		Competitions []string `json:"competitions"`
+	}
+
+	// ProjectedFixture is a player's expected points in an upcoming fixture
+	ProjectedFixture struct {
+		Gw       int     `json:"gw"`
+		Date     string  `json:"date"`
+		Opponent string  `json:"opponent"`
+		Home     bool    `json:"home"`
+		Points   float64 `json:"points"`
 	}
 
 	Stats struct {
@@ -98,16 +88,6 @@ type (
 		CleanSheets      int `json:"clean_sheets"`
 	}
 
-	PlayerFilter struct {
-		Team        string
-		Job         string
-		Cost        int
-		Points      int
-		Games       int
-		Average     int
-		ApplyFilter bool
-	}
-
 	MatchWeek struct {
 		Id            int     `json:"id"`
 		Status        string  `json:"status"`
@@ -153,6 +133,8 @@ type (
 		ID            int        `json:"id"`
 		CompetitionID int        `json:"competition_id"`
 		Name          string     `json:"full_name"`
+		ShortName     string     `json:"short_name"`
+		Abbreviation  string     `json:"abbr"`
 		Stats         SquadStats `json:"stats"`
 	}
 
@@ -171,35 +153,60 @@ type (
 		Name string `json:"name"`
 	}
 
+	// TeamFile is a fantasy team kept in a YAML or JSON file, with players given by name or id
+	TeamFile struct {
+		Bank          float64  `yaml:"bank"`
+		FreeTransfers *int     `yaml:"free_transfers"`
+		Players       []string `yaml:"players"`
+	}
+
 	DataMaps struct {
 		SquadMap      map[int]Squad
 		CompetitonMap map[int]Competition
 	}
 
 	RenderedPlayer struct {
-		Id           int
-		Position     string
-		Name         string
-		Team         string
-		Cost         string
-		TotalPoints  int
-		GamesPlayed  int
-		StarMan      int
-		SevenPlus    int
-		Goals        int
-		Assists      int
-		CleanSheets  int
-		Cards        int
-		Last3Avg     float32
-		Last5Avg     float32
-		TeamFixtures []RenderedMatch
+		Id           int                `json:"id"`
+		Position     string             `json:"position"`
+		Name         string             `json:"name"`
+		Team         string             `json:"team"`
+		Cost         string             `json:"-"`
+		CostMillions float64            `json:"cost"`
+		TotalPoints  int                `json:"total_points"`
+		GamesPlayed  int                `json:"games_played"`
+		StarMan      int                `json:"star_man"`
+		SevenPlus    int                `json:"seven_plus"`
+		Goals        int                `json:"goals"`
+		Assists      int                `json:"assists"`
+		CleanSheets  int                `json:"clean_sheets"`
+		Cards        int                `json:"cards"`
+		Last3Avg     float32            `json:"last_3_avg"`
+		Last5Avg     float32            `json:"last_5_avg"`
+		TeamFixtures []RenderedMatch    `json:"fixtures"`
+		Projected    []ProjectedFixture `json:"projected,omitempty"`
+		Gameweeks    []SpecialGameweek  `json:"blank_and_double_gameweeks,omitempty"`
 	}
 
 	RenderedMatch struct {
-		Gw          int
-		Competition string
-		Fixture     string
-		Result      string
-		Date        string
+		Gw          int             `json:"gw"`
+		Competition string          `json:"competition"`
+		Fixture     string          `json:"fixture"`
+		Result      string          `json:"result"`
+		Date        string          `json:"date"`
+		Kickoff     string          `json:"kickoff"`
+		Venue       string          `json:"venue"`
+		VenueId     int             `json:"venue_id"`
+		Status      string          `json:"status"`
+		Points      int             `json:"points"`
+		Events      []RenderedEvent `json:"events,omitempty"`
+	}
+
+	RenderedEvent struct {
+		Minute int    `json:"min"`
+		Period int    `json:"period"`
+		Event  string `json:"event"`
+		Player string `json:"player"`
+		Team   string `json:"team"`
+		Assist string `json:"assist,omitempty"`
 	}
 )